@@ -0,0 +1,430 @@
+package embeddings
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	sfom_embeddings "github.com/sfomuseum/go-embeddings"
+	"github.com/sfomuseum/go-embeddings/internal/latency"
+	"google.golang.org/grpc/status"
+)
+
+// The file extensions of the images read from directories in bench mode.
+var bench_image_extensions = []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".tif", ".tiff"}
+
+// benchLatency defines summary statistics, in milliseconds, for the time taken by successful requests.
+type benchLatency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+// benchPayload defines summary statistics, in bytes, for the size of the request bodies sent.
+type benchPayload struct {
+	Total int64   `json:"total"`
+	Min   int     `json:"min"`
+	Mean  float64 `json:"mean"`
+	Max   int     `json:"max"`
+}
+
+// benchReport defines the results of a benchmark.
+type benchReport struct {
+	Embedder    string  `json:"embedder"`
+	Modality    string  `json:"modality"`
+	Model       string  `json:"model,omitempty"`
+	Dimensions  int32   `json:"dimensions"`
+	Corpus      int     `json:"corpus"`
+	Concurrency int     `json:"concurrency"`
+	Elapsed     float64 `json:"elapsed_seconds"`
+	Requests    int64   `json:"requests"`
+	Successes   int64   `json:"successes"`
+	Errors      int64   `json:"errors"`
+	// The number of errors for each class of error, for example "http_503", "grpc_Unavailable" or "timeout".
+	ErrorCounts map[string]int64 `json:"error_counts,omitempty"`
+	// Successful requests per second.
+	Throughput float64      `json:"throughput"`
+	Latency    benchLatency `json:"latency"`
+	Payload    benchPayload `json:"payload"`
+}
+
+// benchWorker accumulates the results for a single benchmark worker.
+type benchWorker struct {
+	durations []time.Duration
+	sizes     []int
+	errors    map[string]int64
+}
+
+// benchmarkEmbedder sends requests for each item in the corpus defined by 'paths', in turn, to the embedder defined by
+// 'client_uri' using -concurrency workers until -duration has elapsed or -max-requests requests have been sent. The results
+// are written as JSON to STDOUT and a summary table to STDERR.
+func benchmarkEmbedder(ctx context.Context, client_uri string, modality string, paths []string) error {
+
+	if concurrency < 1 {
+		return fmt.Errorf("Invalid -concurrency flag")
+	}
+
+	if duration <= 0 && max_requests <= 0 {
+		return fmt.Errorf("One of -duration or -max-requests must be greater than zero")
+	}
+
+	corpus, err := benchCorpus(modality, paths)
+
+	if err != nil {
+		return err
+	}
+
+	if len(corpus) == 0 {
+		return fmt.Errorf("Corpus is empty")
+	}
+
+	slog.Debug("Create embedder", "client_uri", sfom_embeddings.RedactURI(client_uri), "precision", precision)
+
+	var report *benchReport
+
+	switch precision {
+	case 32:
+
+		cl, err := sfom_embeddings.NewEmbedder32(ctx, client_uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create embedder, %w", err)
+		}
+
+		defer sfom_embeddings.CloseEmbedder(ctx, cl)
+
+		report = runBenchmark(ctx, cl, modality, corpus)
+
+	case 64:
+
+		cl, err := sfom_embeddings.NewEmbedder64(ctx, client_uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create embedder, %w", err)
+		}
+
+		defer sfom_embeddings.CloseEmbedder(ctx, cl)
+
+		report = runBenchmark(ctx, cl, modality, corpus)
+
+	default:
+		return fmt.Errorf("Invalid or unsupported precision")
+	}
+
+	report.Embedder = sfom_embeddings.RedactURI(client_uri)
+
+	enc := json.NewEncoder(os.Stdout)
+	err = enc.Encode(report)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode report, %w", err)
+	}
+
+	return writeBenchReport(os.Stderr, report)
+}
+
+// runBenchmark sends requests for the items in 'corpus' to 'cl'. Workers stop sending new requests once -duration has
+// elapsed, or -max-requests requests have been sent, but requests which are in progress are allowed to complete.
+func runBenchmark[T sfom_embeddings.Float](ctx context.Context, cl sfom_embeddings.Embedder[T], modality string, corpus [][]byte) *benchReport {
+
+	report := &benchReport{
+		Modality:    modality,
+		Corpus:      len(corpus),
+		Concurrency: concurrency,
+		ErrorCounts: make(map[string]int64),
+	}
+
+	var sent atomic.Int64
+	var once sync.Once
+
+	workers := make([]*benchWorker, concurrency)
+
+	t1 := time.Now()
+	var deadline time.Time
+
+	if duration > 0 {
+		deadline = t1.Add(duration)
+	}
+
+	wg := new(sync.WaitGroup)
+
+	for i := range workers {
+
+		w := &benchWorker{
+			durations: make([]time.Duration, 0),
+			sizes:     make([]int, 0),
+			errors:    make(map[string]int64),
+		}
+
+		workers[i] = w
+
+		wg.Go(func() {
+
+			for {
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				if !deadline.IsZero() && !time.Now().Before(deadline) {
+					return
+				}
+
+				n := sent.Add(1)
+
+				if max_requests > 0 && n > int64(max_requests) {
+					return
+				}
+
+				body := corpus[(n-1)%int64(len(corpus))]
+
+				req := &sfom_embeddings.EmbeddingsRequest{
+					Id:    fmt.Sprintf("bench#%d", n),
+					Model: model,
+					Body:  body,
+					Task:  task,
+				}
+
+				var rsp sfom_embeddings.EmbeddingsResponse[T]
+				var err error
+
+				req_t1 := time.Now()
+
+				switch modality {
+				case modality_image:
+					rsp, err = cl.ImageEmbeddings(ctx, req)
+				default:
+					rsp, err = cl.TextEmbeddings(ctx, req)
+				}
+
+				d := time.Since(req_t1)
+
+				w.sizes = append(w.sizes, len(body))
+
+				if err != nil {
+					slog.Debug("Request failed", "id", req.Id, "error", err)
+					w.errors[benchErrorClass(err)] += 1
+					continue
+				}
+
+				w.durations = append(w.durations, d)
+
+				once.Do(func() {
+					report.Model = rsp.Model()
+					report.Dimensions = rsp.Dimensions()
+				})
+			}
+		})
+	}
+
+	wg.Wait()
+
+	elapsed := time.Since(t1)
+
+	durations := make([]time.Duration, 0)
+	sizes := make([]int, 0)
+
+	for _, w := range workers {
+
+		durations = append(durations, w.durations...)
+		sizes = append(sizes, w.sizes...)
+
+		for k, v := range w.errors {
+			report.ErrorCounts[k] += v
+			report.Errors += v
+		}
+	}
+
+	report.Elapsed = elapsed.Seconds()
+	report.Requests = int64(len(sizes))
+	report.Successes = int64(len(durations))
+
+	if elapsed > 0 {
+		report.Throughput = float64(report.Successes) / elapsed.Seconds()
+	}
+
+	if len(durations) > 0 {
+
+		slices.Sort(durations)
+
+		var total time.Duration
+
+		for _, d := range durations {
+			total += d
+		}
+
+		report.Latency = benchLatency{
+			Mean: latency.Milliseconds(total / time.Duration(len(durations))),
+			P50:  latency.Milliseconds(latency.Percentile(durations, 0.50)),
+			P95:  latency.Milliseconds(latency.Percentile(durations, 0.95)),
+			P99:  latency.Milliseconds(latency.Percentile(durations, 0.99)),
+			Max:  latency.Milliseconds(durations[len(durations)-1]),
+		}
+	}
+
+	if len(sizes) > 0 {
+
+		report.Payload.Min = slices.Min(sizes)
+		report.Payload.Max = slices.Max(sizes)
+
+		for _, sz := range sizes {
+			report.Payload.Total += int64(sz)
+		}
+
+		report.Payload.Mean = float64(report.Payload.Total) / float64(len(sizes))
+	}
+
+	return report
+}
+
+// benchCorpus reads the request bodies for a benchmark. For text, each (non-empty) line in each path is a separate
+// request body and a path of "-" means STDIN. For images, each path is either an image file or a directory which is
+// searched (recursively) for images.
+func benchCorpus(modality string, paths []string) ([][]byte, error) {
+
+	corpus := make([][]byte, 0)
+
+	for _, path := range paths {
+
+		switch modality {
+		case modality_text:
+
+			var r io.Reader
+
+			if path == "-" {
+				r = os.Stdin
+			} else {
+
+				fh, err := os.Open(path)
+
+				if err != nil {
+					return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+				}
+
+				defer fh.Close()
+				r = fh
+			}
+
+			scanner := bufio.NewScanner(r)
+			scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+			for scanner.Scan() {
+
+				text := strings.TrimSpace(scanner.Text())
+
+				if text != "" {
+					corpus = append(corpus, []byte(text))
+				}
+			}
+
+			err := scanner.Err()
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read %s, %w", path, err)
+			}
+
+		case modality_image:
+
+			err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+
+				if err != nil {
+					return err
+				}
+
+				if d.IsDir() {
+					return nil
+				}
+
+				if p != path && !slices.Contains(bench_image_extensions, strings.ToLower(filepath.Ext(p))) {
+					return nil
+				}
+
+				body, err := os.ReadFile(p)
+
+				if err != nil {
+					return err
+				}
+
+				corpus = append(corpus, body)
+				return nil
+			})
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read %s, %w", path, err)
+			}
+
+		default:
+			return nil, fmt.Errorf("Invalid or unsupported modality for bench mode")
+		}
+	}
+
+	return corpus, nil
+}
+
+// benchErrorClass returns a short label used to group 'err' with similar errors.
+func benchErrorClass(err error) string {
+
+	var http_err *sfom_embeddings.HTTPError
+	var circuit_err *sfom_embeddings.CircuitOpenError
+
+	switch {
+	case errors.As(err, &http_err):
+		return fmt.Sprintf("http_%d", http_err.StatusCode)
+	case errors.As(err, &circuit_err):
+		return "circuit_open"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return fmt.Sprintf("grpc_%s", s.Code())
+	}
+
+	return "other"
+}
+
+func writeBenchReport(wr io.Writer, report *benchReport) error {
+
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Embedder\t%s\n", report.Embedder)
+	fmt.Fprintf(tw, "Model\t%s (%d dimensions)\n", report.Model, report.Dimensions)
+	fmt.Fprintf(tw, "Concurrency\t%d\n", report.Concurrency)
+	fmt.Fprintf(tw, "Elapsed\t%.2fs\n", report.Elapsed)
+	fmt.Fprintf(tw, "Requests\t%d (%d successful, %d errors)\n", report.Requests, report.Successes, report.Errors)
+
+	classes := make([]string, 0, len(report.ErrorCounts))
+
+	for k := range report.ErrorCounts {
+		classes = append(classes, k)
+	}
+
+	slices.Sort(classes)
+
+	for _, k := range classes {
+		fmt.Fprintf(tw, "  %s\t%d\n", k, report.ErrorCounts[k])
+	}
+
+	fmt.Fprintf(tw, "Throughput\t%.2f requests/s\n", report.Throughput)
+	fmt.Fprintf(tw, "Latency (ms)\tmean %.1f, p50 %.1f, p95 %.1f, p99 %.1f, max %.1f\n", report.Latency.Mean, report.Latency.P50, report.Latency.P95, report.Latency.P99, report.Latency.Max)
+	fmt.Fprintf(tw, "Payload (bytes)\tmin %d, mean %.0f, max %d, total %d\n", report.Payload.Min, report.Payload.Mean, report.Payload.Max, report.Payload.Total)
+
+	return tw.Flush()
+}
@@ -0,0 +1,158 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sfom_embeddings "github.com/sfomuseum/go-embeddings"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBenchCorpus(t *testing.T) {
+
+	root := t.TempDir()
+
+	text_path := filepath.Join(root, "corpus.txt")
+
+	err := os.WriteFile(text_path, []byte("hello world\n\n  \nsan francisco\n"), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write corpus, %v", err)
+	}
+
+	corpus, err := benchCorpus(modality_text, []string{text_path})
+
+	if err != nil {
+		t.Fatalf("Failed to read text corpus, %v", err)
+	}
+
+	if len(corpus) != 2 || string(corpus[0]) != "hello world" || string(corpus[1]) != "san francisco" {
+		t.Fatalf("Unexpected text corpus, %q", corpus)
+	}
+
+	images := filepath.Join(root, "images")
+	nested := filepath.Join(images, "nested")
+
+	err = os.MkdirAll(nested, 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create images directory, %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(images, "a.jpg"):     "a",
+		filepath.Join(nested, "b.PNG"):     "b",
+		filepath.Join(images, "notes.txt"): "notes",
+	}
+
+	for path, body := range files {
+
+		err := os.WriteFile(path, []byte(body), 0644)
+
+		if err != nil {
+			t.Fatalf("Failed to write %s, %v", path, err)
+		}
+	}
+
+	corpus, err = benchCorpus(modality_image, []string{images})
+
+	if err != nil {
+		t.Fatalf("Failed to read image corpus, %v", err)
+	}
+
+	if len(corpus) != 2 {
+		t.Fatalf("Expected 2 images, got %d", len(corpus))
+	}
+
+	// Files named explicitly are read regardless of their extension
+
+	corpus, err = benchCorpus(modality_image, []string{filepath.Join(images, "notes.txt")})
+
+	if err != nil {
+		t.Fatalf("Failed to read image corpus, %v", err)
+	}
+
+	if len(corpus) != 1 || string(corpus[0]) != "notes" {
+		t.Fatalf("Unexpected image corpus, %q", corpus)
+	}
+
+	_, err = benchCorpus(modality_text, []string{filepath.Join(root, "missing.txt")})
+
+	if err == nil {
+		t.Fatalf("Expected missing corpus to fail")
+	}
+}
+
+func TestBenchErrorClass(t *testing.T) {
+
+	tests := map[string]error{
+		"http_503":         fmt.Errorf("Failed, %w", &sfom_embeddings.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}),
+		"circuit_open":     &sfom_embeddings.CircuitOpenError{},
+		"timeout":          fmt.Errorf("Failed, %w", context.DeadlineExceeded),
+		"canceled":         context.Canceled,
+		"grpc_Unavailable": status.Error(codes.Unavailable, "unavailable"),
+		"other":            errors.New("other"),
+	}
+
+	for expected, err := range tests {
+
+		class := benchErrorClass(err)
+
+		if class != expected {
+			t.Fatalf("Unexpected class for '%v', expected %s but got %s", err, expected, class)
+		}
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+
+	ctx := context.Background()
+
+	concurrency = 2
+	duration = time.Minute
+	max_requests = 10
+	model = ""
+	task = ""
+
+	// Failures are classified by benchErrorClass (above) so the null:// embedder is enough to check that
+	// requests are counted, limited by -max-requests and measured.
+
+	cl, err := sfom_embeddings.NewEmbedder32(ctx, "null://")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	corpus := [][]byte{
+		[]byte("a"),
+		[]byte("bbb"),
+	}
+
+	report := runBenchmark(ctx, cl, modality_text, corpus)
+
+	if report.Requests != 10 || report.Successes != 10 || report.Errors != 0 {
+		t.Fatalf("Unexpected counts, %d requests, %d successes, %d errors", report.Requests, report.Successes, report.Errors)
+	}
+
+	if report.Model != "null" {
+		t.Fatalf("Unexpected model '%s'", report.Model)
+	}
+
+	if report.Corpus != 2 || report.Concurrency != 2 {
+		t.Fatalf("Unexpected corpus (%d) or concurrency (%d)", report.Corpus, report.Concurrency)
+	}
+
+	if report.Payload.Min != 1 || report.Payload.Max != 3 || report.Payload.Total != 20 {
+		t.Fatalf("Unexpected payload, %v", report.Payload)
+	}
+
+	if report.Latency.Max < report.Latency.P50 {
+		t.Fatalf("Unexpected latency, %v", report.Latency)
+	}
+}
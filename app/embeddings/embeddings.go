@@ -1,19 +1,28 @@
 package embeddings
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	sfom_embeddings "github.com/sfomuseum/go-embeddings"
+	"github.com/sfomuseum/go-embeddings/columnar"
+	"github.com/sfomuseum/go-embeddings/eval"
 	"github.com/sfomuseum/go-flags/flagset"
 )
 
+const modality_text string = "text"
+const modality_image string = "image"
+
 func Run(ctx context.Context) error {
 	fs := DefaultFlagSet()
 	return RunWithFlagSet(ctx, fs)
@@ -29,16 +38,84 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 		slog.Debug("Verbose logging enabled")
 	}
 
+	if len(args) == 0 {
+		return fmt.Errorf("Missing action")
+	}
+
+	if !sfom_embeddings.IsValidFormat(format) && !columnar.IsValidFormat(format) {
+		return fmt.Errorf("Invalid or unsupported format")
+	}
+
+	if task != "" && !sfom_embeddings.IsValidTask(task) {
+		return fmt.Errorf("Invalid or unsupported task")
+	}
+
+	// Use a local copy so that the default is not appended to the (package-level) flag value
+
+	uris := []string(client_uris)
+
+	if len(uris) == 0 {
+		uris = []string{"null://"}
+	}
+
 	action := args[0]
 
-	var embeddings_req *sfom_embeddings.EmbeddingsRequest
-	var embeddings_rsp any
-	var embeddings_err error
+	if action != "eval" && len(uris) > 1 {
+		return fmt.Errorf("Multiple -client-uri flags are only supported by the eval action")
+	}
+
+	client_uri := uris[0]
+
+	var modality string
+	var requests iter.Seq2[*sfom_embeddings.EmbeddingsRequest, error]
 
-	var body []byte
+	bulk := false
 
 	switch action {
-	case "text":
+	case "scripts":
+
+		if len(args) != 2 {
+			return fmt.Errorf("Missing target directory for scripts")
+		}
+
+		err := sfom_embeddings.WriteScripts(args[1])
+
+		if err != nil {
+			return fmt.Errorf("Failed to write scripts, %w", err)
+		}
+
+		for _, name := range sfom_embeddings.ScriptNames() {
+			slog.Debug("Wrote script", "path", filepath.Join(args[1], name))
+		}
+
+		return nil
+
+	case "bench":
+
+		if len(args) < 3 {
+			return fmt.Errorf("Bench mode requires a modality (text or image) and one or more paths")
+		}
+
+		switch args[1] {
+		case modality_text, modality_image:
+			// pass
+		default:
+			return fmt.Errorf("Invalid or unsupported modality for bench mode")
+		}
+
+		return benchmarkEmbedder(ctx, client_uri, args[1], args[2:])
+
+	case "eval":
+
+		if len(args) != 2 {
+			return fmt.Errorf("Missing dataset for eval")
+		}
+
+		return evaluateEmbedders(ctx, uris, args[1])
+
+	case modality_text:
+
+		var body []byte
 
 		switch len(args) {
 		case 2:
@@ -68,11 +145,12 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 			body = []byte(strings.Join(args[1:], " "))
 		}
 
-		embeddings_req = &sfom_embeddings.EmbeddingsRequest{
+		modality = modality_text
+		requests = singleRequest(&sfom_embeddings.EmbeddingsRequest{
 			Body: body,
-		}
+		})
 
-	case "image":
+	case modality_image:
 
 		body, err := os.ReadFile(args[1])
 
@@ -80,17 +158,34 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 			return fmt.Errorf("Failed to read file, %v", err)
 		}
 
-		embeddings_req = &sfom_embeddings.EmbeddingsRequest{
+		modality = modality_image
+		requests = singleRequest(&sfom_embeddings.EmbeddingsRequest{
 			Body: body,
+		})
+
+	case "bulk":
+
+		if len(args) < 3 {
+			return fmt.Errorf("Bulk mode requires a modality (text or image) and one or more paths")
 		}
 
+		modality = args[1]
+
+		switch modality {
+		case modality_text, modality_image:
+			// pass
+		default:
+			return fmt.Errorf("Invalid or unsupported modality for bulk mode")
+		}
+
+		bulk = true
+		requests = bulkRequests(modality, args[2:])
+
 	default:
 		return fmt.Errorf("Invalid or unsuported action")
 	}
 
-	if model != "" {
-		embeddings_req.Model = model
-	}
+	slog.Debug("Create embedder", "client_uri", sfom_embeddings.RedactURI(client_uri), "precision", precision)
 
 	switch precision {
 	case 32:
@@ -101,14 +196,9 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 			return fmt.Errorf("Failed to create embedder, %w", err)
 		}
 
-		switch action {
-		case "text":
-			embeddings_rsp, embeddings_err = cl.TextEmbeddings(ctx, embeddings_req)
-		case "image":
-			embeddings_rsp, embeddings_err = cl.ImageEmbeddings(ctx, embeddings_req)
-		default:
-			return fmt.Errorf("Invalid or unsupported action")
-		}
+		defer sfom_embeddings.CloseEmbedder(ctx, cl)
+
+		return deriveEmbeddings(ctx, cl, modality, requests, bulk)
 
 	case 64:
 
@@ -118,25 +208,66 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 			return fmt.Errorf("Failed to create embedder, %w", err)
 		}
 
-		switch action {
-		case "text":
-			embeddings_rsp, embeddings_err = cl.TextEmbeddings(ctx, embeddings_req)
-		case "image":
-			embeddings_rsp, embeddings_err = cl.ImageEmbeddings(ctx, embeddings_req)
-		default:
-			return fmt.Errorf("Invalid or unsupported action")
-		}
+		defer sfom_embeddings.CloseEmbedder(ctx, cl)
+
+		return deriveEmbeddings(ctx, cl, modality, requests, bulk)
 
 	default:
 		return fmt.Errorf("Invalid or unsupported precision")
 	}
+}
+
+// deriveEmbeddings derives embeddings for each request in 'requests' using 'cl' and writes them to STDOUT.
+func deriveEmbeddings[T sfom_embeddings.Float](ctx context.Context, cl sfom_embeddings.Embedder[T], modality string, requests iter.Seq2[*sfom_embeddings.EmbeddingsRequest, error], bulk bool) error {
+
+	wr, err := newResponseWriter[T](os.Stdout, format, bulk)
 
-	if embeddings_err != nil {
-		return fmt.Errorf("Failed to derive embeddings, %v", embeddings_err)
+	if err != nil {
+		return err
 	}
 
-	enc := json.NewEncoder(os.Stdout)
-	err := enc.Encode(embeddings_rsp)
+	for req, err := range requests {
+
+		if err != nil {
+			return err
+		}
+
+		if model != "" {
+			req.Model = model
+		}
+
+		if task != "" {
+			req.Task = task
+		}
+
+		var rsp sfom_embeddings.EmbeddingsResponse[T]
+
+		switch modality {
+		case modality_text:
+			rsp, err = cl.TextEmbeddings(ctx, req)
+		case modality_image:
+			rsp, err = cl.ImageEmbeddings(ctx, req)
+		default:
+			return fmt.Errorf("Invalid or unsupported action")
+		}
+
+		if err != nil {
+
+			if bulk {
+				return fmt.Errorf("Failed to derive embeddings for %s, %v", req.Id, err)
+			}
+
+			return fmt.Errorf("Failed to derive embeddings, %v", err)
+		}
+
+		err = wr.Write(rsp)
+
+		if err != nil {
+			return fmt.Errorf("Failed to encode embeddings, %v", err)
+		}
+	}
+
+	err = wr.Close()
 
 	if err != nil {
 		return fmt.Errorf("Failed to encode embeddings, %v", err)
@@ -144,3 +275,165 @@ func RunWithFlagSet(ctx context.Context, fs *flag.FlagSet) error {
 
 	return nil
 }
+
+// evaluateEmbedders evaluates the retrieval quality of each embedder defined by 'uris' using the dataset
+// in 'path', writing the results as JSON to STDOUT and a comparison table to STDERR.
+func evaluateEmbedders(ctx context.Context, uris []string, path string) error {
+
+	ds, err := eval.ReadDataset(path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to read dataset, %w", err)
+	}
+
+	k_values := make([]int, 0)
+
+	for _, str_k := range strings.Split(k, ",") {
+
+		v, err := strconv.Atoi(strings.TrimSpace(str_k))
+
+		if err != nil || v < 1 {
+			return fmt.Errorf("Invalid -k flag")
+		}
+
+		k_values = append(k_values, v)
+	}
+
+	results := make([]*eval.Result, len(uris))
+
+	for idx, uri := range uris {
+
+		opts := &eval.Options{
+			Label: sfom_embeddings.RedactURI(uri),
+			K:     k_values,
+			Model: model,
+		}
+
+		slog.Debug("Evaluate embedder", "client_uri", opts.Label, "precision", precision)
+
+		var r *eval.Result
+
+		switch precision {
+		case 32:
+
+			cl, err := sfom_embeddings.NewEmbedder32(ctx, uri)
+
+			if err != nil {
+				return fmt.Errorf("Failed to create embedder for %s, %w", opts.Label, err)
+			}
+
+			r, err = evaluateEmbedder(ctx, cl, ds, opts)
+
+			if err != nil {
+				return fmt.Errorf("Failed to evaluate %s, %w", opts.Label, err)
+			}
+
+		case 64:
+
+			cl, err := sfom_embeddings.NewEmbedder64(ctx, uri)
+
+			if err != nil {
+				return fmt.Errorf("Failed to create embedder for %s, %w", opts.Label, err)
+			}
+
+			r, err = evaluateEmbedder(ctx, cl, ds, opts)
+
+			if err != nil {
+				return fmt.Errorf("Failed to evaluate %s, %w", opts.Label, err)
+			}
+
+		default:
+			return fmt.Errorf("Invalid or unsupported precision")
+		}
+
+		results[idx] = r
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	err = enc.Encode(results)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode results, %w", err)
+	}
+
+	return eval.WriteTable(os.Stderr, results)
+}
+
+// evaluateEmbedder evaluates 'cl' using 'ds' and closes it.
+func evaluateEmbedder[T sfom_embeddings.Float](ctx context.Context, cl sfom_embeddings.Embedder[T], ds *eval.Dataset, opts *eval.Options) (*eval.Result, error) {
+
+	defer sfom_embeddings.CloseEmbedder(ctx, cl)
+
+	return eval.Evaluate(ctx, cl, ds, opts)
+}
+
+func singleRequest(req *sfom_embeddings.EmbeddingsRequest) iter.Seq2[*sfom_embeddings.EmbeddingsRequest, error] {
+
+	return func(yield func(*sfom_embeddings.EmbeddingsRequest, error) bool) {
+		yield(req, nil)
+	}
+}
+
+// bulkRequests yields a request for each file in 'paths', using the path as the request ID. If the modality
+// is "text" and a path is "-" then each (non-empty) line read from STDIN is yielded as a separate request
+// whose ID is "stdin#" followed by the line number.
+func bulkRequests(modality string, paths []string) iter.Seq2[*sfom_embeddings.EmbeddingsRequest, error] {
+
+	return func(yield func(*sfom_embeddings.EmbeddingsRequest, error) bool) {
+
+		for _, path := range paths {
+
+			if path == "-" && modality == modality_text {
+
+				scanner := bufio.NewScanner(os.Stdin)
+				scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+				line := 0
+
+				for scanner.Scan() {
+
+					line += 1
+					text := strings.TrimSpace(scanner.Text())
+
+					if text == "" {
+						continue
+					}
+
+					req := &sfom_embeddings.EmbeddingsRequest{
+						Id:   fmt.Sprintf("stdin#%d", line),
+						Body: []byte(text),
+					}
+
+					if !yield(req, nil) {
+						return
+					}
+				}
+
+				err := scanner.Err()
+
+				if err != nil {
+					yield(nil, fmt.Errorf("Failed to read STDIN, %v", err))
+					return
+				}
+
+				continue
+			}
+
+			body, err := os.ReadFile(path)
+
+			if err != nil {
+				yield(nil, fmt.Errorf("Failed to read %s, %v", path, err))
+				return
+			}
+
+			req := &sfom_embeddings.EmbeddingsRequest{
+				Id:   path,
+				Body: body,
+			}
+
+			if !yield(req, nil) {
+				return
+			}
+		}
+	}
+}
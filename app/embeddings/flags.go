@@ -4,27 +4,48 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/sfomuseum/go-flags/flagset"
+	"github.com/sfomuseum/go-flags/multi"
 )
 
-var client_uri string
+var client_uris multi.MultiString
 var precision int
 var model string
+var task string
 var verbose bool
+var format string
+var k string
+var concurrency int
+var duration time.Duration
+var max_requests int
 
 func DefaultFlagSet() *flag.FlagSet {
 
 	fs := flagset.NewFlagSet("embeddings")
 
-	fs.StringVar(&client_uri, "client-uri", "null://", "A registered sfomuseum/go-embeddings.Embedder[T] URI.")
+	// multi.MultiString appends to its existing value so reset it in case the flag set is created more than once
+	client_uris = nil
+
+	fs.Var(&client_uris, "client-uri", "A registered sfomuseum/go-embeddings.Embedder[T] URI. May be repeated when using the eval action. Default is null://.")
 	fs.StringVar(&model, "model", "", "An optional model to specify when generating embeddings.")
+	fs.StringVar(&task, "task", "", "An optional task (query, document, classification, clustering) used by embedders to apply model-specific text prefixes.")
 	fs.IntVar(&precision, "precision", 32, "The float-precision to use to for the embeddings that are returned.")
+	fs.StringVar(&format, "format", "json", "The format to write embeddings in. Valid options are: json, raw (little-endian floats), npy, npz, record (length-prefixed binary records), parquet, arrow (Arrow IPC stream).")
+	fs.StringVar(&k, "k", "1,5,10", "A comma-separated list of cut-offs used to calculate recall and nDCG when using the eval action.")
+	fs.IntVar(&concurrency, "concurrency", 1, "The number of concurrent requests to send when using the bench action.")
+	fs.DurationVar(&duration, "duration", 30*time.Second, "How long to send requests for when using the bench action. A value of 0 means no limit, in which case -max-requests must be set.")
+	fs.IntVar(&max_requests, "max-requests", 0, "The maximum number of requests to send when using the bench action. A value of 0 means no limit.")
 	fs.BoolVar(&verbose, "verbose", false, "Enable verbose (debug) logging.")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Derive vector embeddings for a text string or image file.\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n\t%s [options] [text|image] arg(N) arg(N)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s [options] bulk [text|image] path(N) path(N)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s [options] bench [text|image] path(N) path(N)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s [options] eval dataset.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s [options] scripts target_directory\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Valid options are:\n")
 		fs.PrintDefaults()
 	}
@@ -0,0 +1,62 @@
+package embeddings
+
+import (
+	"fmt"
+	"io"
+
+	sfom_embeddings "github.com/sfomuseum/go-embeddings"
+	"github.com/sfomuseum/go-embeddings/columnar"
+)
+
+// responseWriter writes one or more `EmbeddingsResponse` values.
+type responseWriter[T sfom_embeddings.Float] interface {
+	Write(sfom_embeddings.EmbeddingsResponse[T]) error
+	Close() error
+}
+
+// newResponseWriter returns a `responseWriter` for 'format'. If 'bulk' is true "npy" output is written as a single
+// two-dimensional array and "json" output is written as newline-delimited JSON.
+func newResponseWriter[T sfom_embeddings.Float](wr io.Writer, format string, bulk bool) (responseWriter[T], error) {
+
+	if columnar.IsValidFormat(format) {
+		return columnar.NewWriter[T](wr, format, nil)
+	}
+
+	switch {
+	case bulk && format == sfom_embeddings.FORMAT_NPY:
+		return &npyMatrixWriter[T]{writer: wr}, nil
+	case bulk && format == sfom_embeddings.FORMAT_NPZ:
+		return nil, fmt.Errorf("The npz format is not supported in bulk mode")
+	default:
+		return &encodeWriter[T]{writer: wr, format: format}, nil
+	}
+}
+
+// encodeWriter writes each response, as it is received, using `EncodeResponse`.
+type encodeWriter[T sfom_embeddings.Float] struct {
+	writer io.Writer
+	format string
+}
+
+func (w *encodeWriter[T]) Write(rsp sfom_embeddings.EmbeddingsResponse[T]) error {
+	return sfom_embeddings.EncodeResponse(w.writer, rsp, w.format)
+}
+
+func (w *encodeWriter[T]) Close() error {
+	return nil
+}
+
+// npyMatrixWriter buffers embeddings and writes them as a two-dimensional NumPy array when closed.
+type npyMatrixWriter[T sfom_embeddings.Float] struct {
+	writer  io.Writer
+	vectors [][]T
+}
+
+func (w *npyMatrixWriter[T]) Write(rsp sfom_embeddings.EmbeddingsResponse[T]) error {
+	w.vectors = append(w.vectors, rsp.Embeddings())
+	return nil
+}
+
+func (w *npyMatrixWriter[T]) Close() error {
+	return sfom_embeddings.WriteNPYMatrix(w.writer, w.vectors)
+}
@@ -0,0 +1,440 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Send requests to each backend in turn.
+	BALANCE_ROUND_ROBIN string = "round-robin"
+	// Send requests to the backend with the fewest outstanding requests.
+	BALANCE_LEAST_OUTSTANDING string = "least-outstanding"
+	// Send requests to each backend in proportion to its weight.
+	BALANCE_WEIGHTED string = "weighted"
+)
+
+// How long to wait before checking whether a backend has capacity, if none do, in the absence of other signals.
+const balance_wait_interval time.Duration = 50 * time.Millisecond
+
+// BalanceBackendStats defines statistics for an individual backend of a `BalanceEmbedder` instance.
+type BalanceBackendStats struct {
+	// A label for the backend, derived from its URI without any query parameters.
+	Label string `json:"label"`
+	// The weight assigned to the backend.
+	Weight int `json:"weight"`
+	// The total number of requests sent to the backend.
+	Requests int64 `json:"requests"`
+	// The total number of requests which failed.
+	Failures int64 `json:"failures"`
+	// The number of requests currently in progress.
+	Outstanding int `json:"outstanding"`
+	// The number of times the backend has been ejected.
+	Ejections int64 `json:"ejections"`
+	// If non-zero, the time until which the backend is ejected.
+	EjectedUntil time.Time `json:"ejected_until,omitzero"`
+}
+
+// BalanceEmbedder implements the `Embedder` interface by spreading requests across multiple equivalent `Embedder` instances.
+type BalanceEmbedder[T Float] struct {
+	Embedder[T]
+	backends    []*balanceBackend[T]
+	strategy    string
+	eject_after int
+	eject_for   time.Duration
+	mu          sync.Mutex
+	next        int
+	released    chan struct{}
+}
+
+type balanceBackend[T Float] struct {
+	embedder             Embedder[T]
+	stats                BalanceBackendStats
+	max_concurrency      int
+	current_weight       int
+	consecutive_failures int
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "balance", NewBalanceEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "balance32", NewBalanceEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "balance64", NewBalanceEmbedder[float64])
+}
+
+// NewBalanceEmbedder creates a new `BalanceEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	balance://?client-uri={CLIENT_URI}&client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of an underlying `Embedder`. May be repeated.
+// * `strategy` – The strategy used to pick a backend. Valid options are "round-robin", "least-outstanding" and "weighted". Default is "round-robin".
+// * `weight` – The (integer) weight of each backend, in the same order as the `client-uri` parameters. Only used by the "weighted" strategy. Default is 1.
+// * `max-concurrency` – The maximum number of concurrent requests for each backend. If a single value is provided it is applied to all backends, otherwise
+// there must be one value for each backend. Requests wait until a backend has capacity. Default is 0 (no limit).
+// * `eject-after` – The number of consecutive retryable errors after which a backend is ejected. Default is 3. A value of 0 disables ejection.
+// * `eject-for` – The number of seconds a backend is ejected for. Default is 30.
+func NewBalanceEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	client_uris := q["client-uri"]
+
+	if len(client_uris) == 0 {
+		return nil, fmt.Errorf("A minimum of (1) ?client-uri= parameters is required")
+	}
+
+	strategy := BALANCE_ROUND_ROBIN
+
+	if q.Has("strategy") {
+		strategy = q.Get("strategy")
+	}
+
+	switch strategy {
+	case BALANCE_ROUND_ROBIN, BALANCE_LEAST_OUTSTANDING, BALANCE_WEIGHTED:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?strategy= parameter")
+	}
+
+	weights, err := perBackendInts(q["weight"], len(client_uris), 1, 1)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ?weight= parameter, %w", err)
+	}
+
+	concurrency, err := perBackendInts(q["max-concurrency"], len(client_uris), 0, 0)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ?max-concurrency= parameter, %w", err)
+	}
+
+	eject_after := 3
+
+	if q.Has("eject-after") {
+
+		v, err := strconv.Atoi(q.Get("eject-after"))
+
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("Invalid ?eject-after= parameter")
+		}
+
+		eject_after = v
+	}
+
+	eject_for := 30 * time.Second
+
+	if q.Has("eject-for") {
+
+		v, err := strconv.Atoi(q.Get("eject-for"))
+
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("Invalid ?eject-for= parameter")
+		}
+
+		eject_for = time.Duration(v) * time.Second
+	}
+
+	backends := make([]*balanceBackend[T], len(client_uris))
+
+	for idx, client_uri := range client_uris {
+
+		cl, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+		if err != nil {
+
+			// Close the backends which have already been created so that they are not left running
+
+			closeEmbedders(ctx, balanceEmbedders(backends[:idx]))
+			return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+		}
+
+		backends[idx] = &balanceBackend[T]{
+			embedder:        cl,
+			max_concurrency: concurrency[idx],
+			stats: BalanceBackendStats{
+				Label:  fmt.Sprintf("%d:%s", idx, embedderLabel(client_uri)),
+				Weight: weights[idx],
+			},
+		}
+	}
+
+	e := &BalanceEmbedder[T]{
+		backends:    backends,
+		strategy:    strategy,
+		eject_after: eject_after,
+		eject_for:   eject_for,
+		released:    make(chan struct{}, 1),
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives text embeddings using the next backend chosen by the balancing strategy.
+func (e *BalanceEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, func(cl Embedder[T]) (EmbeddingsResponse[T], error) {
+		return cl.TextEmbeddings(ctx, req)
+	})
+}
+
+// ImageEmbeddings derives image embeddings using the next backend chosen by the balancing strategy.
+func (e *BalanceEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, func(cl Embedder[T]) (EmbeddingsResponse[T], error) {
+		return cl.ImageEmbeddings(ctx, req)
+	})
+}
+
+// Stats returns a snapshot of the statistics for each backend.
+func (e *BalanceEmbedder[T]) Stats() []BalanceBackendStats {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]BalanceBackendStats, len(e.backends))
+
+	for idx, b := range e.backends {
+
+		stats[idx] = b.stats
+
+		if !now.Before(b.stats.EjectedUntil) {
+			stats[idx].EjectedUntil = time.Time{}
+		}
+	}
+
+	return stats
+}
+
+// Close closes each of the underlying embedders, returning any errors joined together.
+func (e *BalanceEmbedder[T]) Close(ctx context.Context) error {
+	return closeEmbedders(ctx, balanceEmbedders(e.backends))
+}
+
+func balanceEmbedders[T Float](backends []*balanceBackend[T]) []Embedder[T] {
+
+	embedders := make([]Embedder[T], len(backends))
+
+	for idx, b := range backends {
+		embedders[idx] = b.embedder
+	}
+
+	return embedders
+}
+
+func (e *BalanceEmbedder[T]) embeddings(ctx context.Context, fn func(Embedder[T]) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	b, err := e.acquire(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := fn(b.embedder)
+
+	e.release(b, err)
+	return rsp, err
+}
+
+// acquire waits until a backend has capacity and returns it, having incremented its outstanding requests.
+func (e *BalanceEmbedder[T]) acquire(ctx context.Context) (*balanceBackend[T], error) {
+
+	for {
+
+		e.mu.Lock()
+		b := e.pick(time.Now())
+
+		if b != nil {
+			b.stats.Outstanding += 1
+			b.stats.Requests += 1
+		}
+
+		e.mu.Unlock()
+
+		if b != nil {
+			return b, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-e.released:
+		case <-time.After(balance_wait_interval):
+		}
+	}
+}
+
+func (e *BalanceEmbedder[T]) release(b *balanceBackend[T], err error) {
+
+	e.mu.Lock()
+
+	b.stats.Outstanding -= 1
+
+	switch {
+	case err == nil:
+		b.consecutive_failures = 0
+	case IsRetryableError(err):
+
+		b.stats.Failures += 1
+		b.consecutive_failures += 1
+
+		if e.eject_after > 0 && b.consecutive_failures >= e.eject_after {
+
+			b.consecutive_failures = 0
+			b.stats.Ejections += 1
+			b.stats.EjectedUntil = time.Now().Add(e.eject_for)
+
+			slog.Warn("Ejecting backend", "backend", b.stats.Label, "until", b.stats.EjectedUntil, "error", err)
+		}
+
+	default:
+		b.stats.Failures += 1
+	}
+
+	e.mu.Unlock()
+
+	select {
+	case e.released <- struct{}{}:
+	default:
+	}
+}
+
+// pick returns the next backend according to the balancing strategy, or nil if no backend has capacity. Ejected
+// backends are skipped unless all the backends are ejected. It must be called with the lock held.
+func (e *BalanceEmbedder[T]) pick(now time.Time) *balanceBackend[T] {
+
+	candidates := make([]*balanceBackend[T], 0, len(e.backends))
+	all_ejected := true
+
+	for _, b := range e.backends {
+
+		if !now.Before(b.stats.EjectedUntil) {
+			all_ejected = false
+			break
+		}
+	}
+
+	for _, b := range e.backends {
+
+		if !all_ejected && now.Before(b.stats.EjectedUntil) {
+			continue
+		}
+
+		if b.max_concurrency > 0 && b.stats.Outstanding >= b.max_concurrency {
+			continue
+		}
+
+		candidates = append(candidates, b)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch e.strategy {
+	case BALANCE_LEAST_OUTSTANDING:
+
+		// Rotate the starting point so that ties are spread across backends
+
+		start := e.next % len(candidates)
+		e.next += 1
+
+		var best *balanceBackend[T]
+
+		for i := range candidates {
+
+			b := candidates[(start+i)%len(candidates)]
+
+			if best == nil || b.stats.Outstanding < best.stats.Outstanding {
+				best = b
+			}
+		}
+
+		return best
+
+	case BALANCE_WEIGHTED:
+
+		// Smooth weighted round-robin, as used by nginx
+
+		total := 0
+
+		var best *balanceBackend[T]
+
+		for _, b := range candidates {
+
+			b.current_weight += b.stats.Weight
+			total += b.stats.Weight
+
+			if best == nil || b.current_weight > best.current_weight {
+				best = b
+			}
+		}
+
+		best.current_weight -= total
+		return best
+
+	default:
+
+		b := candidates[e.next%len(candidates)]
+		e.next += 1
+
+		return b
+	}
+}
+
+// perBackendInts parses 'values' returning one integer for each of 'count' backends. If 'values' is empty 'default_value'
+// is used for all backends; if it contains a single value that value is used for all backends. Values must be at least 'min_value'.
+func perBackendInts(values []string, count int, default_value int, min_value int) ([]int, error) {
+
+	ints := make([]int, count)
+
+	switch len(values) {
+	case 0:
+
+		for i := range ints {
+			ints[i] = default_value
+		}
+
+		return ints, nil
+
+	case 1, count:
+		// pass
+	default:
+		return nil, fmt.Errorf("Expected 1 or %d values, got %d", count, len(values))
+	}
+
+	for i := range ints {
+
+		str_v := values[0]
+
+		if len(values) == count {
+			str_v = values[i]
+		}
+
+		v, err := strconv.Atoi(str_v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if v < min_value {
+			return nil, fmt.Errorf("Value must be at least %d", min_value)
+		}
+
+		ints[i] = v
+	}
+
+	return ints, nil
+}
@@ -0,0 +1,187 @@
+package embeddings
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBalanceEmbedderRoundRobin(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "balance://?client-uri=testing://%3Fmodel=a&client-uri=testing://%3Fmodel=b")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	for i, expected := range []string{"a", "b", "a", "b"} {
+
+		rsp, err := emb.TextEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive embeddings, %v", err)
+		}
+
+		if rsp.Model() != expected {
+			t.Fatalf("Unexpected backend for request %d, expected %s but got %s", i, expected, rsp.Model())
+		}
+	}
+}
+
+func TestBalanceEmbedderWeighted(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "balance://?client-uri=testing://%3Fmodel=a&client-uri=testing://%3Fmodel=b&strategy=weighted&weight=3&weight=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	counts := make(map[string]int)
+
+	for i := 0; i < 8; i++ {
+
+		rsp, err := emb.TextEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive embeddings, %v", err)
+		}
+
+		counts[rsp.Model()] += 1
+	}
+
+	if counts["a"] != 6 || counts["b"] != 2 {
+		t.Fatalf("Unexpected distribution, %v", counts)
+	}
+}
+
+func TestBalanceEmbedderEjection(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "balance://?client-uri=testing://%3Fmodel=a%26error=retryable&client-uri=testing://%3Fmodel=b&eject-after=1&eject-for=60")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected first request to fail")
+	}
+
+	for i := 0; i < 3; i++ {
+
+		rsp, err := emb.TextEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Expected ejected backend to be skipped, %v", err)
+		}
+
+		if rsp.Model() != "b" {
+			t.Fatalf("Unexpected backend, %s", rsp.Model())
+		}
+	}
+
+	stats := emb.(*BalanceEmbedder[float32]).Stats()
+
+	if stats[0].Ejections != 1 || stats[0].Failures != 1 || stats[0].EjectedUntil.IsZero() {
+		t.Fatalf("Unexpected stats for ejected backend, %v", stats[0])
+	}
+
+	if stats[1].Requests != 3 {
+		t.Fatalf("Unexpected stats for healthy backend, %v", stats[1])
+	}
+}
+
+func TestBalanceEmbedderConcurrency(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "balance://?client-uri=testing://%3Fdelay=50&max-concurrency=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	start := time.Now()
+
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 3; i++ {
+
+		wg.Go(func() {
+
+			_, err := emb.TextEmbeddings(ctx, req)
+
+			if err != nil {
+				t.Errorf("Failed to derive embeddings, %v", err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if time.Since(start) < 150*time.Millisecond {
+		t.Fatalf("Expected requests to be serialized by concurrency limit")
+	}
+}
+
+func TestBalanceEmbedderClose(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	// Backends which have already been created are closed if a later backend can not be created
+
+	_, err := NewEmbedder32(ctx, "balance://?client-uri=testing://&client-uri=unknown://")
+
+	if err == nil {
+		t.Fatalf("Expected unknown backend to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected backends not to be left open")
+	}
+
+	// Every backend is closed even if closing an earlier one fails
+
+	emb, err := NewEmbedder32(ctx, "balance://?client-uri=testing://%3Fclose-error=true&client-uri=testing://%3Fclose-error=true")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	err = CloseEmbedder(ctx, emb)
+
+	if err == nil || strings.Count(err.Error(), "Testing close error") != 2 {
+		t.Fatalf("Expected both close errors, got %v", err)
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected all backends to be closed")
+	}
+}
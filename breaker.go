@@ -0,0 +1,271 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Requests are sent to the underlying embedder.
+	CIRCUIT_CLOSED string = "closed"
+	// Requests fail immediately with a `CircuitOpenError`.
+	CIRCUIT_OPEN string = "open"
+	// A limited number of probe requests are sent to the underlying embedder to determine whether it has recovered.
+	CIRCUIT_HALF_OPEN string = "half-open"
+)
+
+// BreakerEmbedder implements the `Embedder` interface by wrapping another `Embedder` instance in a circuit breaker. After
+// a number of consecutive retryable failures the circuit "opens" and requests fail immediately with a `CircuitOpenError`
+// rather than waiting on an unresponsive server. After a cooldown period a limited number of probe requests are allowed
+// through and, if they succeed, the circuit is closed again.
+type BreakerEmbedder[T Float] struct {
+	Embedder[T]
+	embedder  Embedder[T]
+	label     string
+	failures  int
+	cooldown  time.Duration
+	probes    int
+	successes int
+	mu        sync.Mutex
+	state     string
+	// The number of consecutive failures in the closed state
+	consecutive_failures int
+	// The number of successful probes in the half-open state
+	consecutive_successes int
+	// The number of probes in progress in the half-open state
+	inflight_probes int
+	opened_until    time.Time
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "breaker", NewBreakerEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "breaker32", NewBreakerEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "breaker64", NewBreakerEmbedder[float64])
+}
+
+// NewBreakerEmbedder creates a new `BreakerEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	breaker://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `failures` – The number of consecutive retryable failures after which the circuit opens. Default is 5.
+// * `cooldown` – The number of seconds the circuit stays open before probe requests are allowed. Default is 30.
+// * `probes` – The maximum number of concurrent probe requests while the circuit is half-open. Default is 1.
+// * `successes` – The number of successful probe requests required to close the circuit. Default is 1.
+func NewBreakerEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &BreakerEmbedder[T]{
+		embedder:  emb,
+		label:     embedderLabel(client_uri),
+		failures:  5,
+		cooldown:  30 * time.Second,
+		probes:    1,
+		successes: 1,
+		state:     CIRCUIT_CLOSED,
+	}
+
+	cooldown := int(e.cooldown.Seconds())
+
+	int_params := map[string]*int{
+		"failures":  &e.failures,
+		"cooldown":  &cooldown,
+		"probes":    &e.probes,
+		"successes": &e.successes,
+	}
+
+	for k, ptr := range int_params {
+
+		if !q.Has(k) {
+			continue
+		}
+
+		v, err := strconv.Atoi(q.Get(k))
+
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("Invalid ?%s= parameter", k)
+		}
+
+		*ptr = v
+	}
+
+	e.cooldown = time.Duration(cooldown) * time.Second
+
+	return e, nil
+}
+
+// TextEmbeddings passes 'req' to the underlying embedder unless the circuit is open.
+func (e *BreakerEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, func() (EmbeddingsResponse[T], error) {
+		return e.embedder.TextEmbeddings(ctx, req)
+	})
+}
+
+// ImageEmbeddings passes 'req' to the underlying embedder unless the circuit is open.
+func (e *BreakerEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, func() (EmbeddingsResponse[T], error) {
+		return e.embedder.ImageEmbeddings(ctx, req)
+	})
+}
+
+// State returns the current state of the circuit: "closed", "open" or "half-open".
+func (e *BreakerEmbedder[T]) State() string {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state == CIRCUIT_OPEN && !time.Now().Before(e.opened_until) {
+		return CIRCUIT_HALF_OPEN
+	}
+
+	return e.state
+}
+
+// Close closes the underlying embedder.
+func (e *BreakerEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *BreakerEmbedder[T]) embeddings(ctx context.Context, fn func() (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	probe, err := e.allow(time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := fn()
+
+	e.record(probe, err)
+	return rsp, err
+}
+
+// allow returns nil if a request may be sent to the underlying embedder, and whether it is a probe request, or a `CircuitOpenError`.
+func (e *BreakerEmbedder[T]) allow(now time.Time) (bool, error) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state == CIRCUIT_OPEN && !now.Before(e.opened_until) {
+		e.transition(CIRCUIT_HALF_OPEN, nil)
+	}
+
+	switch e.state {
+	case CIRCUIT_CLOSED:
+		return false, nil
+	case CIRCUIT_HALF_OPEN:
+
+		if e.inflight_probes < e.probes {
+			e.inflight_probes += 1
+			return true, nil
+		}
+	}
+
+	return false, &CircuitOpenError{
+		Label: e.label,
+		Until: e.opened_until,
+	}
+}
+
+// record updates the state of the circuit with the result of a request.
+func (e *BreakerEmbedder[T]) record(probe bool, err error) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if probe {
+		e.inflight_probes -= 1
+	}
+
+	// Cancelled requests say nothing about the health of the embedder
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	failed := IsRetryableError(err)
+
+	switch e.state {
+	case CIRCUIT_CLOSED:
+
+		if !failed {
+			e.consecutive_failures = 0
+			return
+		}
+
+		e.consecutive_failures += 1
+
+		if e.consecutive_failures >= e.failures {
+			e.transition(CIRCUIT_OPEN, err)
+		}
+
+	case CIRCUIT_HALF_OPEN:
+
+		// Ignore the results of requests which started before the circuit opened
+
+		if !probe {
+			return
+		}
+
+		if failed {
+			e.transition(CIRCUIT_OPEN, err)
+			return
+		}
+
+		e.consecutive_successes += 1
+
+		if e.consecutive_successes >= e.successes {
+			e.transition(CIRCUIT_CLOSED, nil)
+		}
+	}
+}
+
+// transition changes the state of the circuit and resets its counters. It must be called with the lock held.
+func (e *BreakerEmbedder[T]) transition(state string, err error) {
+
+	previous := e.state
+
+	e.state = state
+	e.consecutive_failures = 0
+	e.consecutive_successes = 0
+
+	switch state {
+	case CIRCUIT_OPEN:
+		e.opened_until = time.Now().Add(e.cooldown)
+		slog.Warn("Circuit breaker opened", "embedder", e.label, "from", previous, "until", e.opened_until, "error", err)
+	case CIRCUIT_HALF_OPEN:
+		slog.Info("Circuit breaker half-open, probing embedder", "embedder", e.label)
+	case CIRCUIT_CLOSED:
+		e.opened_until = time.Time{}
+		slog.Info("Circuit breaker closed", "embedder", e.label, "from", previous)
+	}
+}
@@ -0,0 +1,162 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "breaker://?client-uri=testing://%3Ferror=retryable%26failures=3&failures=2&successes=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	br := emb.(*BreakerEmbedder[float32])
+	backend := br.embedder.(*testingEmbedder[float32])
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	for i := 0; i < 2; i++ {
+
+		_, err := emb.TextEmbeddings(ctx, req)
+
+		var open_err *CircuitOpenError
+
+		if err == nil || errors.As(err, &open_err) {
+			t.Fatalf("Expected backend error, got %v", err)
+		}
+	}
+
+	if br.State() != CIRCUIT_OPEN {
+		t.Fatalf("Expected circuit to be open, got %s", br.State())
+	}
+
+	// Requests fail immediately without reaching the backend
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	var open_err *CircuitOpenError
+
+	if !errors.As(err, &open_err) {
+		t.Fatalf("Expected CircuitOpenError, got %v", err)
+	}
+
+	if !IsRetryableError(err) {
+		t.Fatalf("Expected CircuitOpenError to be retryable")
+	}
+
+	if backend.calls.Load() != 2 {
+		t.Fatalf("Expected 2 backend calls, got %d", backend.calls.Load())
+	}
+
+	// Skip the cooldown; the probe fails (the backend's third failure) so the circuit opens again
+
+	br.opened_until = time.Now()
+
+	if br.State() != CIRCUIT_HALF_OPEN {
+		t.Fatalf("Expected circuit to be half-open, got %s", br.State())
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil || errors.As(err, &open_err) {
+		t.Fatalf("Expected backend error, got %v", err)
+	}
+
+	if br.State() != CIRCUIT_OPEN {
+		t.Fatalf("Expected circuit to be open, got %s", br.State())
+	}
+
+	// The next probe succeeds and the circuit closes
+
+	br.opened_until = time.Now()
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if br.State() != CIRCUIT_CLOSED {
+		t.Fatalf("Expected circuit to be closed, got %s", br.State())
+	}
+}
+
+func TestBreakerEmbedderProbes(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "breaker://?client-uri=testing://%3Fdelay=100&probes=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	br := emb.(*BreakerEmbedder[float32])
+	br.state = CIRCUIT_OPEN
+	br.opened_until = time.Now()
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	done := make(chan error)
+
+	go func() {
+		_, err := emb.TextEmbeddings(ctx, req)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Only one probe is allowed at a time
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	var open_err *CircuitOpenError
+
+	if !errors.As(err, &open_err) {
+		t.Fatalf("Expected CircuitOpenError while probe is in progress, got %v", err)
+	}
+
+	err = <-done
+
+	if err != nil {
+		t.Fatalf("Probe failed, %v", err)
+	}
+
+	if br.State() != CIRCUIT_CLOSED {
+		t.Fatalf("Expected circuit to be closed, got %s", br.State())
+	}
+}
+
+func TestBreakerEmbedderFatal(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "breaker://?client-uri=testing://%3Ferror=fatal&failures=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	emb.TextEmbeddings(ctx, req)
+
+	// Non-retryable errors (for example invalid input) do not open the circuit
+
+	if emb.(*BreakerEmbedder[float32]).State() != CIRCUIT_CLOSED {
+		t.Fatalf("Expected circuit to be closed")
+	}
+}
@@ -0,0 +1,413 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Split text in to (whitespace-delimited) tokens.
+	CHUNK_TOKENS string = "tokens"
+	// Split text in to (unicode) characters.
+	CHUNK_CHARACTERS string = "characters"
+	// Split text in to sentences.
+	CHUNK_SENTENCES string = "sentences"
+)
+
+const (
+	// Pool chunk embeddings using their element-wise mean.
+	POOL_MEAN string = "mean"
+	// Pool chunk embeddings using their element-wise maximum.
+	POOL_MAX string = "max"
+	// Pool chunk embeddings using their element-wise mean, weighted by the length of each chunk.
+	POOL_WEIGHTED string = "weighted"
+	// Do not pool chunk embeddings.
+	POOL_NONE string = "none"
+)
+
+var re_token = regexp.MustCompile(`\S+`)
+
+// TextChunk defines the byte offsets of a chunk of text.
+type TextChunk struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// EmbeddingsChunk defines the embeddings for a chunk of text and its (byte) offsets.
+type EmbeddingsChunk[T Float] struct {
+	TextChunk
+	Embeddings []T `json:"embeddings"`
+}
+
+// ChunkedEmbeddingsResponse is an `EmbeddingsResponse` implementation which includes the embeddings
+// (and offsets) for each chunk of text used to derive the final embeddings.
+type ChunkedEmbeddingsResponse[T Float] struct {
+	CommonEmbeddingsResponse[T]
+	Chunks []*EmbeddingsChunk[T] `json:"chunks,omitempty"`
+}
+
+// ChunkEmbedder implements the `Embedder` interface by splitting text in to chunks, deriving embeddings for
+// each chunk using another `Embedder` instance and then pooling those embeddings.
+type ChunkEmbedder[T Float] struct {
+	Embedder[T]
+	embedder       Embedder[T]
+	method         string
+	size           int
+	overlap        int
+	pool           string
+	include_chunks bool
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "chunk", NewChunkEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "chunk32", NewChunkEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "chunk64", NewChunkEmbedder[float64])
+}
+
+// NewChunkEmbedder creates a new `ChunkEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	chunk://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings for each chunk. Required.
+// * `method` – The method used to split text. Valid options are "tokens", "characters" and "sentences". Default is "tokens".
+// * `size` – The number of units (tokens, characters or sentences) in each chunk. Default is 256.
+// * `overlap` – The number of units shared by consecutive chunks. Default is 0.
+// * `pool` – The method used to pool chunk embeddings. Valid options are "mean", "max", "weighted" and "none". Default is "mean".
+// * `chunks` – A boolean flag indicating whether the embeddings for each chunk should be included in responses. Always true if `pool` is "none".
+func NewChunkEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	method := CHUNK_TOKENS
+
+	if q.Has("method") {
+		method = q.Get("method")
+	}
+
+	switch method {
+	case CHUNK_TOKENS, CHUNK_CHARACTERS, CHUNK_SENTENCES:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?method= parameter")
+	}
+
+	size := 256
+
+	if q.Has("size") {
+
+		v, err := strconv.Atoi(q.Get("size"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?size= parameter, %w", err)
+		}
+
+		size = v
+	}
+
+	overlap := 0
+
+	if q.Has("overlap") {
+
+		v, err := strconv.Atoi(q.Get("overlap"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?overlap= parameter, %w", err)
+		}
+
+		overlap = v
+	}
+
+	if size < 1 || overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("Invalid ?size= and ?overlap= parameters, size must be greater than zero and overlap")
+	}
+
+	pool := POOL_MEAN
+
+	if q.Has("pool") {
+		pool = q.Get("pool")
+	}
+
+	switch pool {
+	case POOL_MEAN, POOL_MAX, POOL_WEIGHTED, POOL_NONE:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?pool= parameter")
+	}
+
+	include_chunks := pool == POOL_NONE
+
+	if q.Has("chunks") {
+
+		v, err := strconv.ParseBool(q.Get("chunks"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?chunks= parameter, %w", err)
+		}
+
+		include_chunks = v || pool == POOL_NONE
+	}
+
+	// Create the underlying embedder last so that it is not left running if any of the parameters above are invalid
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &ChunkEmbedder[T]{
+		embedder:       emb,
+		method:         method,
+		size:           size,
+		overlap:        overlap,
+		pool:           pool,
+		include_chunks: include_chunks,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings splits the body of 'req' in to chunks, derives embeddings for each chunk and returns a
+// `ChunkedEmbeddingsResponse` instance. If the pooling method is "none" the response will have an empty
+// list of (top-level) embeddings and the embeddings for each chunk should be read from its `Chunks` property.
+func (e *ChunkEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	chunks, err := ChunkText(req.Body, e.method, e.size, e.overlap)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("Request body is empty")
+	}
+
+	rsp := &ChunkedEmbeddingsResponse[T]{
+		CommonEmbeddingsResponse: CommonEmbeddingsResponse[T]{
+			CommonId:      req.Id,
+			CommonCreated: time.Now().Unix(),
+		},
+	}
+
+	vectors := make([][]T, len(chunks))
+	weights := make([]float64, len(chunks))
+
+	for idx, c := range chunks {
+
+		chunk_req := &EmbeddingsRequest{
+			Id:    req.Id,
+			Model: req.Model,
+			Body:  req.Body[c.Start:c.End],
+			Task:  req.Task,
+		}
+
+		chunk_rsp, err := e.embedder.TextEmbeddings(ctx, chunk_req)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive embeddings for chunk %d (%d-%d), %w", idx, c.Start, c.End, err)
+		}
+
+		rsp.CommonModel = chunk_rsp.Model()
+		rsp.CommonPrecision = chunk_rsp.Precision()
+
+		vectors[idx] = chunk_rsp.Embeddings()
+		weights[idx] = float64(c.End - c.Start)
+
+		if e.include_chunks {
+			rsp.Chunks = append(rsp.Chunks, &EmbeddingsChunk[T]{
+				TextChunk:  c,
+				Embeddings: chunk_rsp.Embeddings(),
+			})
+		}
+	}
+
+	var pooled []T
+
+	switch e.pool {
+	case POOL_MAX:
+		pooled, err = MaxPool(vectors)
+	case POOL_WEIGHTED:
+		pooled, err = WeightedMeanPool(vectors, weights)
+	case POOL_NONE:
+		pooled = make([]T, 0)
+	default:
+		pooled, err = MeanPool(vectors)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to pool embeddings, %w", err)
+	}
+
+	rsp.CommonEmbeddings = pooled
+	return rsp, nil
+}
+
+// ImageEmbeddings passes 'req' to the underlying embedder without modification.
+func (e *ChunkEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embedder.ImageEmbeddings(ctx, req)
+}
+
+// Close closes the underlying embedder.
+func (e *ChunkEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+// ChunkText splits 'body' in to chunks of 'size' units, where units are defined by 'method' ("tokens", "characters"
+// or "sentences"), with consecutive chunks sharing 'overlap' units. The (byte) offsets of each chunk are returned.
+func ChunkText(body []byte, method string, size int, overlap int) ([]TextChunk, error) {
+
+	if size < 1 || overlap < 0 || overlap >= size {
+		return nil, fmt.Errorf("Size must be greater than zero and overlap")
+	}
+
+	var units []TextChunk
+
+	switch method {
+	case CHUNK_TOKENS:
+		units = tokenUnits(body)
+	case CHUNK_CHARACTERS:
+		units = characterUnits(body)
+	case CHUNK_SENTENCES:
+		units = sentenceUnits(body)
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported chunking method '%s'", method)
+	}
+
+	chunks := make([]TextChunk, 0)
+	step := size - overlap
+
+	for i := 0; i < len(units); i += step {
+
+		j := min(i+size, len(units))
+
+		chunks = append(chunks, TextChunk{
+			Start: units[i].Start,
+			End:   units[j-1].End,
+		})
+
+		if j == len(units) {
+			break
+		}
+	}
+
+	return chunks, nil
+}
+
+func tokenUnits(body []byte) []TextChunk {
+
+	units := make([]TextChunk, 0)
+
+	for _, loc := range re_token.FindAllIndex(body, -1) {
+		units = append(units, TextChunk{Start: loc[0], End: loc[1]})
+	}
+
+	return units
+}
+
+func characterUnits(body []byte) []TextChunk {
+
+	units := make([]TextChunk, 0)
+
+	for i := 0; i < len(body); {
+		_, sz := utf8.DecodeRune(body[i:])
+		units = append(units, TextChunk{Start: i, End: i + sz})
+		i += sz
+	}
+
+	return units
+}
+
+// sentenceUnits splits 'body' on runs of '.', '!' or '?' followed by whitespace (or the end of the text).
+// Leading whitespace is excluded from each sentence.
+func sentenceUnits(body []byte) []TextChunk {
+
+	units := make([]TextChunk, 0)
+	start := -1
+
+	for i := 0; i < len(body); {
+
+		r, sz := utf8.DecodeRune(body[i:])
+
+		if start == -1 {
+
+			if !unicode.IsSpace(r) {
+				start = i
+			}
+
+			i += sz
+			continue
+		}
+
+		i += sz
+
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+
+		// Consume any trailing terminal punctuation
+
+		for i < len(body) {
+
+			next, next_sz := utf8.DecodeRune(body[i:])
+
+			if next != '.' && next != '!' && next != '?' {
+				break
+			}
+
+			i += next_sz
+		}
+
+		if i == len(body) {
+			break
+		}
+
+		next, _ := utf8.DecodeRune(body[i:])
+
+		if unicode.IsSpace(next) {
+			units = append(units, TextChunk{Start: start, End: i})
+			start = -1
+		}
+	}
+
+	if start != -1 {
+
+		end := len(body)
+
+		for end > start {
+
+			r, sz := utf8.DecodeLastRune(body[:end])
+
+			if !unicode.IsSpace(r) {
+				break
+			}
+
+			end -= sz
+		}
+
+		units = append(units, TextChunk{Start: start, End: end})
+	}
+
+	return units
+}
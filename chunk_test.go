@@ -0,0 +1,105 @@
+package embeddings
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChunkText(t *testing.T) {
+
+	body := []byte("The walrus is large. It lives in the Arctic!  Does it swim? Yes")
+
+	tests := []struct {
+		method  string
+		size    int
+		overlap int
+		expect  []string
+	}{
+		{CHUNK_TOKENS, 4, 1, []string{"The walrus is large.", "large. It lives in", "in the Arctic!  Does", "Does it swim? Yes"}},
+		{CHUNK_CHARACTERS, 30, 0, []string{"The walrus is large. It lives ", "in the Arctic!  Does it swim? ", "Yes"}},
+		{CHUNK_SENTENCES, 2, 1, []string{"The walrus is large. It lives in the Arctic!", "It lives in the Arctic!  Does it swim?", "Does it swim? Yes"}},
+	}
+
+	for _, test := range tests {
+
+		chunks, err := ChunkText(body, test.method, test.size, test.overlap)
+
+		if err != nil {
+			t.Fatalf("Failed to chunk text using %s, %v", test.method, err)
+		}
+
+		if len(chunks) != len(test.expect) {
+			t.Fatalf("Unexpected number of chunks for %s: %d", test.method, len(chunks))
+		}
+
+		for idx, c := range chunks {
+
+			str := string(body[c.Start:c.End])
+
+			if str != test.expect[idx] {
+				t.Fatalf("Unexpected chunk %d for %s: '%s'", idx, test.method, str)
+			}
+		}
+	}
+}
+
+func TestChunkEmbeddings(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "chunk://?client-uri=testing://&method=tokens&size=2&pool=weighted&chunks=true")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("aa bb c"),
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	// Chunks are "aa bb" (length 5) and "c" (length 1) so the first (length) element
+	// weighted by chunk length is ((5 * 5) + (1 * 1)) / 6
+
+	e := rsp.Embeddings()
+
+	if e[0] != float32(26.0/6.0) || e[1] != 1 {
+		t.Fatalf("Unexpected embeddings, %v", e)
+	}
+
+	chunked_rsp, ok := rsp.(*ChunkedEmbeddingsResponse[float32])
+
+	if !ok {
+		t.Fatalf("Unexpected response type")
+	}
+
+	if len(chunked_rsp.Chunks) != 2 {
+		t.Fatalf("Unexpected number of chunks: %d", len(chunked_rsp.Chunks))
+	}
+
+	if chunked_rsp.Chunks[1].Start != 6 || chunked_rsp.Chunks[1].End != 7 {
+		t.Fatalf("Unexpected offsets for second chunk")
+	}
+}
+
+func TestChunkEmbedderInvalidParameters(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	_, err := NewEmbedder32(ctx, "chunk://?client-uri=testing://&method=words")
+
+	if err == nil {
+		t.Fatalf("Expected invalid ?method= parameter to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected underlying embedder not to be left open")
+	}
+}
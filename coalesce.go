@@ -0,0 +1,276 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CoalesceEmbedder implements the `Embedder` interface by coalescing identical, concurrent requests (same modality,
+// model, task and body) in to a single call to another `Embedder` instance. Each caller receives its own copy of
+// the response with its own request ID.
+type CoalesceEmbedder[T Float] struct {
+	Embedder[T]
+	embedder Embedder[T]
+	group    *singleflight.Group
+	timeout  time.Duration
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "coalesce", NewCoalesceEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "coalesce32", NewCoalesceEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "coalesce64", NewCoalesceEmbedder[float64])
+}
+
+// NewCoalesceEmbedder creates a new `CoalesceEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	coalesce://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `timeout` – The maximum number of seconds a shared call to the underlying embedder may take. Shared calls also inherit the deadline,
+// if any, of the request that started them. Default is 60. A value of 0 means no timeout.
+func NewCoalesceEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	timeout := 60 * time.Second
+
+	if q.Has("timeout") {
+
+		v, err := strconv.Atoi(q.Get("timeout"))
+
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("Invalid ?timeout= parameter")
+		}
+
+		timeout = time.Duration(v) * time.Second
+	}
+
+	client_uri := q.Get("client-uri")
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &CoalesceEmbedder[T]{
+		embedder: emb,
+		group:    new(singleflight.Group),
+		timeout:  timeout,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives text embeddings for 'req', sharing the result with any identical requests in progress.
+func (e *CoalesceEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "text", e.embedder.TextEmbeddings)
+}
+
+// ImageEmbeddings derives image embeddings for 'req', sharing the result with any identical requests in progress.
+func (e *CoalesceEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "image", e.embedder.ImageEmbeddings)
+}
+
+// Close closes the underlying embedder.
+func (e *CoalesceEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *CoalesceEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, modality string, fn func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	key := coalesceKey(req, modality)
+
+	// The shared call is not cancelled if the caller that started it goes away since other
+	// callers may be waiting on it. Each caller stops waiting when its own context is done.
+	// The shared call does keep the deadline of the caller that started it, capped by the
+	// timeout, so that a backend which hangs does not block identical requests forever.
+
+	ch := e.group.DoChan(key, func() (any, error) {
+
+		shared_ctx, cancel := e.sharedContext(ctx)
+		defer cancel()
+
+		return fn(shared_ctx, req)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+
+		if res.Err != nil {
+			return nil, res.Err
+		}
+
+		if res.Shared {
+			slog.Debug("Coalesced embeddings request", "id", req.Id, "modality", modality)
+		}
+
+		rsp, ok := res.Val.(EmbeddingsResponse[T])
+
+		if !ok || rsp == nil {
+			return nil, fmt.Errorf("Underlying embedder returned an empty response")
+		}
+
+		return copyEmbeddingsResponse(rsp, req.Id), nil
+	}
+}
+
+// sharedContext returns a context, which is not cancelled when 'ctx' is, for a shared call started by 'ctx'.
+func (e *CoalesceEmbedder[T]) sharedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+
+	var deadline time.Time
+
+	if d, ok := ctx.Deadline(); ok {
+		deadline = d
+	}
+
+	if e.timeout > 0 {
+
+		d := time.Now().Add(e.timeout)
+
+		if deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+
+	shared_ctx := context.WithoutCancel(ctx)
+
+	if deadline.IsZero() {
+		return shared_ctx, func() {}
+	}
+
+	return context.WithDeadline(shared_ctx, deadline)
+}
+
+// coalesceKey returns a key identifying requests which will produce identical embeddings.
+func coalesceKey(req *EmbeddingsRequest, modality string) string {
+
+	h := sha256.Sum256(req.Body)
+	return fmt.Sprintf("%s#%s#%s#%s", modality, req.Model, req.Task, hex.EncodeToString(h[:]))
+}
+
+// copyEmbeddingsResponse returns a deep copy of 'rsp' with its ID set to 'id', so that callers sharing a response can
+// not modify each other's embeddings. Responses which are pointers to structs embedding `CommonEmbeddingsResponse` (for
+// example `ChunkedEmbeddingsResponse`) keep their type and any additional properties, including slices such as `Chunks`
+// and `Regions`, are copied as well; other implementations are copied to a new `CommonEmbeddingsResponse`.
+func copyEmbeddingsResponse[T Float](rsp EmbeddingsResponse[T], id string) EmbeddingsResponse[T] {
+
+	v := reflect.ValueOf(rsp)
+
+	if v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Struct {
+
+		c := deepCopyValue(v)
+		id_field := c.Elem().FieldByName("CommonId")
+
+		if id_field.IsValid() && id_field.CanSet() && id_field.Kind() == reflect.String {
+
+			copy_rsp, ok := c.Interface().(EmbeddingsResponse[T])
+
+			if ok {
+				id_field.SetString(id)
+				return copy_rsp
+			}
+		}
+	}
+
+	copy_rsp := &CommonEmbeddingsResponse[T]{
+		CommonId:         id,
+		CommonEmbeddings: slices.Clone(rsp.Embeddings()),
+		CommonModel:      rsp.Model(),
+		CommonCreated:    rsp.Created(),
+		CommonPrecision:  rsp.Precision(),
+	}
+
+	return copy_rsp
+}
+
+// deepCopyValue returns a copy of 'v' in which pointers, slices, maps and exported struct fields are copied recursively.
+// Interface values and unexported struct fields are copied as-is.
+func deepCopyValue(v reflect.Value) reflect.Value {
+
+	switch v.Kind() {
+	case reflect.Pointer:
+
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Elem().Type())
+		c.Elem().Set(deepCopyValue(v.Elem()))
+		return c
+
+	case reflect.Struct:
+
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+
+		for i := range c.NumField() {
+
+			f := c.Field(i)
+
+			if f.CanSet() {
+				f.Set(deepCopyValue(v.Field(i)))
+			}
+		}
+
+		return c
+
+	case reflect.Slice:
+
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+
+		return c
+
+	case reflect.Map:
+
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+
+		return c
+
+	default:
+		return v
+	}
+}
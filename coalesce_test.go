@@ -0,0 +1,207 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCoalesceEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "coalesce://?client-uri=testing://%3Fdelay=100")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	backend := emb.(*CoalesceEmbedder[float32]).embedder.(*testingEmbedder[float32])
+
+	workers := 8
+	responses := make([]EmbeddingsResponse[float32], workers)
+	errs := make([]error, workers)
+
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < workers; i++ {
+
+		wg.Add(1)
+
+		go func(i int) {
+
+			defer wg.Done()
+
+			req := &EmbeddingsRequest{
+				Id:   fmt.Sprintf("%d", i),
+				Body: []byte("Hello world"),
+			}
+
+			responses[i], errs[i] = emb.TextEmbeddings(ctx, req)
+		}(i)
+	}
+
+	wg.Wait()
+
+	if backend.calls.Load() != 1 {
+		t.Fatalf("Expected 1 backend call, got %d", backend.calls.Load())
+	}
+
+	for i, rsp := range responses {
+
+		if errs[i] != nil {
+			t.Fatalf("Failed to derive embeddings for worker %d, %v", i, errs[i])
+		}
+
+		if rsp.Id() != fmt.Sprintf("%d", i) {
+			t.Fatalf("Unexpected ID for worker %d: %s", i, rsp.Id())
+		}
+	}
+
+	// Responses do not share embeddings
+
+	responses[0].Embeddings()[0] = -1
+
+	if responses[1].Embeddings()[0] == -1 {
+		t.Fatalf("Responses share the same embeddings")
+	}
+
+	// Different modalities and bodies are not coalesced
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	_, err = emb.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive image embeddings, %v", err)
+	}
+
+	req.Body = []byte("Goodbye world")
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if backend.calls.Load() != 3 {
+		t.Fatalf("Expected 3 backend calls, got %d", backend.calls.Load())
+	}
+}
+
+func TestCoalesceEmbedderCopyResponse(t *testing.T) {
+
+	rsp := &TiledEmbeddingsResponse[float32]{
+		CommonEmbeddingsResponse: CommonEmbeddingsResponse[float32]{
+			CommonId:         "a",
+			CommonEmbeddings: []float32{1, 2, 3},
+		},
+		Regions: []*EmbeddingsRegion[float32]{
+			{ImageRegion: ImageRegion{Width: 1, Height: 1}},
+		},
+	}
+
+	copy_rsp := copyEmbeddingsResponse[float32](rsp, "b")
+
+	tiled, ok := copy_rsp.(*TiledEmbeddingsResponse[float32])
+
+	if !ok {
+		t.Fatalf("Expected TiledEmbeddingsResponse, got %T", copy_rsp)
+	}
+
+	if tiled.Id() != "b" || rsp.Id() != "a" {
+		t.Fatalf("Unexpected IDs: %s, %s", tiled.Id(), rsp.Id())
+	}
+
+	if len(tiled.Regions) != 1 {
+		t.Fatalf("Expected regions to be copied")
+	}
+
+	tiled.Regions[0].Width = 2
+
+	if rsp.Regions[0].Width != 1 {
+		t.Fatalf("Copy shares regions with original")
+	}
+
+	tiled.CommonEmbeddings[0] = -1
+
+	if rsp.CommonEmbeddings[0] != 1 {
+		t.Fatalf("Copy shares embeddings with original")
+	}
+}
+
+func TestCoalesceEmbedderDeadline(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "coalesce://?client-uri=testing://%3Fdelay=5000")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	backend := emb.(*CoalesceEmbedder[float32]).embedder.(*testingEmbedder[float32])
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	// The shared call inherits the deadline of the request that started it so a backend which hangs
+	// does not block later, identical, requests
+
+	for i := 1; i <= 2; i++ {
+
+		req_ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+
+		_, err = emb.TextEmbeddings(req_ctx, req)
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected deadline exceeded error, got %v", err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		if backend.calls.Load() != int64(i) {
+			t.Fatalf("Expected %d backend calls, got %d", i, backend.calls.Load())
+		}
+	}
+
+	// Requests without a deadline are bounded by ?timeout=
+
+	emb, err = NewEmbedder32(ctx, "coalesce://?client-uri=testing://%3Fdelay=5000&timeout=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	t1 := time.Now()
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(t1) > 3*time.Second {
+		t.Fatalf("Expected request to time out after 1 second, got %v after %v", err, time.Since(t1))
+	}
+}
+
+func TestCoalesceEmbedderEmptyResponse(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "coalesce://?client-uri=testing://%3Ferror=empty")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, &EmbeddingsRequest{Body: []byte("Hello world")})
+
+	if err == nil {
+		t.Fatalf("Expected empty response to fail")
+	}
+}
@@ -0,0 +1,205 @@
+// Package columnar provides writers for storing `EmbeddingsResponse` values as Apache Arrow IPC streams
+// and Apache Parquet files. It is a separate package so that the (large) Apache Arrow dependencies are
+// only included by applications which need them.
+package columnar
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/sfomuseum/go-embeddings"
+)
+
+const (
+	// Apache Parquet files.
+	FORMAT_PARQUET string = "parquet"
+	// Apache Arrow IPC streams.
+	FORMAT_ARROW string = "arrow"
+)
+
+// The default number of rows to buffer before writing a record batch (or Parquet row group).
+const DEFAULT_BATCH_SIZE int = 1024
+
+// Writer defines an interface for writing `EmbeddingsResponse` values in a columnar format.
+type Writer[T embeddings.Float] interface {
+	// Write adds a response to the writer. Responses are buffered and written in batches.
+	Write(embeddings.EmbeddingsResponse[T]) error
+	// Close writes any buffered responses and finalizes the output. It does not close the underlying `io.Writer`.
+	Close() error
+}
+
+// WriterOptions defines options for creating new `Writer` instances.
+type WriterOptions struct {
+	// The number of rows to buffer before writing a record batch (or Parquet row group). Default is 1024.
+	BatchSize int
+	// The number of dimensions in each embedding. If 0 the dimensions of the first response written are used.
+	Dimensions int
+}
+
+// IsValidFormat returns a boolean value indicating whether 'format' is a columnar format supported by this package.
+func IsValidFormat(format string) bool {
+
+	switch format {
+	case FORMAT_PARQUET, FORMAT_ARROW:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewWriter returns a new `Writer` instance for 'format' which writes to 'wr'.
+func NewWriter[T embeddings.Float](wr io.Writer, format string, opts *WriterOptions) (Writer[T], error) {
+
+	switch format {
+	case FORMAT_PARQUET:
+		return NewParquetWriter[T](wr, opts)
+	case FORMAT_ARROW:
+		return NewIPCWriter[T](wr, opts)
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported format '%s'", format)
+	}
+}
+
+// Schema returns the Arrow schema used to store embeddings with 'dimensions' as T (float32 or float64) values.
+// The schema has the following columns: id (string), model (string), precision (string), created (int64) and
+// embeddings (a fixed-size list of float32 or float64 values).
+func Schema[T embeddings.Float](dimensions int) *arrow.Schema {
+
+	var value_type arrow.DataType = arrow.PrimitiveTypes.Float64
+
+	var v T
+
+	if _, ok := any(v).(float32); ok {
+		value_type = arrow.PrimitiveTypes.Float32
+	}
+
+	fields := []arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String},
+		{Name: "model", Type: arrow.BinaryTypes.String},
+		{Name: "precision", Type: arrow.BinaryTypes.String},
+		{Name: "created", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "embeddings", Type: arrow.FixedSizeListOf(int32(dimensions), value_type)},
+	}
+
+	return arrow.NewSchema(fields, nil)
+}
+
+// batchWriter buffers responses in an Arrow record builder and hands record batches to a format-specific function.
+type batchWriter[T embeddings.Float] struct {
+	batch_size int
+	dimensions int
+	builder    *array.RecordBuilder
+	count      int
+	// open is called with the schema when the first response is written.
+	open func(*arrow.Schema) error
+	// write is called with each record batch.
+	write func(arrow.RecordBatch) error
+}
+
+func newBatchWriter[T embeddings.Float](opts *WriterOptions) *batchWriter[T] {
+
+	bw := &batchWriter[T]{
+		batch_size: DEFAULT_BATCH_SIZE,
+	}
+
+	if opts != nil {
+
+		if opts.BatchSize > 0 {
+			bw.batch_size = opts.BatchSize
+		}
+
+		bw.dimensions = opts.Dimensions
+	}
+
+	return bw
+}
+
+func (bw *batchWriter[T]) Write(rsp embeddings.EmbeddingsResponse[T]) error {
+
+	vector := rsp.Embeddings()
+
+	if bw.builder == nil {
+
+		if bw.dimensions == 0 {
+			bw.dimensions = len(vector)
+		}
+
+		if bw.dimensions == 0 {
+			return fmt.Errorf("Can not write embeddings with zero dimensions")
+		}
+
+		schema := Schema[T](bw.dimensions)
+
+		err := bw.open(schema)
+
+		if err != nil {
+			return err
+		}
+
+		bw.builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	}
+
+	if len(vector) != bw.dimensions {
+		return fmt.Errorf("Embeddings have %d dimensions, expected %d", len(vector), bw.dimensions)
+	}
+
+	bw.builder.Field(0).(*array.StringBuilder).Append(rsp.Id())
+	bw.builder.Field(1).(*array.StringBuilder).Append(rsp.Model())
+	bw.builder.Field(2).(*array.StringBuilder).Append(rsp.Precision())
+	bw.builder.Field(3).(*array.Int64Builder).Append(rsp.Created())
+
+	list_builder := bw.builder.Field(4).(*array.FixedSizeListBuilder)
+	list_builder.Append(true)
+
+	switch vb := list_builder.ValueBuilder().(type) {
+	case *array.Float32Builder:
+
+		for _, f := range vector {
+			vb.Append(float32(f))
+		}
+
+	case *array.Float64Builder:
+
+		for _, f := range vector {
+			vb.Append(float64(f))
+		}
+	}
+
+	bw.count += 1
+
+	if bw.count >= bw.batch_size {
+		return bw.flush()
+	}
+
+	return nil
+}
+
+func (bw *batchWriter[T]) flush() error {
+
+	if bw.builder == nil || bw.count == 0 {
+		return nil
+	}
+
+	rec := bw.builder.NewRecordBatch()
+	defer rec.Release()
+
+	bw.count = 0
+
+	err := bw.write(rec)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write record batch, %w", err)
+	}
+
+	return nil
+}
+
+func (bw *batchWriter[T]) release() {
+
+	if bw.builder != nil {
+		bw.builder.Release()
+	}
+}
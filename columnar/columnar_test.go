@@ -0,0 +1,181 @@
+package columnar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/sfomuseum/go-embeddings"
+)
+
+func testingResponses(count int) []embeddings.EmbeddingsResponse[float32] {
+
+	responses := make([]embeddings.EmbeddingsResponse[float32], count)
+
+	for i := 0; i < count; i++ {
+
+		responses[i] = &embeddings.CommonEmbeddingsResponse[float32]{
+			CommonId:         fmt.Sprintf("%d", i),
+			CommonModel:      "testing",
+			CommonCreated:    int64(1767225600 + i),
+			CommonPrecision:  "float32",
+			CommonEmbeddings: []float32{float32(i), 1, 2},
+		}
+	}
+
+	return responses
+}
+
+func TestIPCWriter(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	opts := &WriterOptions{
+		BatchSize: 2,
+	}
+
+	wr, err := NewWriter[float32](&buf, FORMAT_ARROW, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	for _, rsp := range testingResponses(5) {
+
+		err := wr.Write(rsp)
+
+		if err != nil {
+			t.Fatalf("Failed to write response, %v", err)
+		}
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	r, err := ipc.NewReader(&buf)
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	defer r.Release()
+
+	rows := 0
+	batches := 0
+
+	for r.Next() {
+
+		rec := r.RecordBatch()
+
+		ids := rec.Column(0).(*array.String)
+		vectors := rec.Column(4).(*array.FixedSizeList)
+		values := vectors.ListValues().(*array.Float32)
+
+		for i := 0; i < int(rec.NumRows()); i++ {
+
+			if ids.Value(i) != fmt.Sprintf("%d", rows) {
+				t.Fatalf("Unexpected id at row %d: %s", rows, ids.Value(i))
+			}
+
+			if values.Value(i*3) != float32(rows) {
+				t.Fatalf("Unexpected embeddings at row %d", rows)
+			}
+
+			rows += 1
+		}
+
+		batches += 1
+	}
+
+	if rows != 5 || batches != 3 {
+		t.Fatalf("Unexpected number of rows (%d) or batches (%d)", rows, batches)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+
+	wr, err := NewWriter[float32](&buf, FORMAT_PARQUET, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	for _, rsp := range testingResponses(5) {
+
+		err := wr.Write(rsp)
+
+		if err != nil {
+			t.Fatalf("Failed to write response, %v", err)
+		}
+	}
+
+	err = wr.Close()
+
+	if err != nil {
+		t.Fatalf("Failed to close writer, %v", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+
+	tbl, err := pqarrow.ReadTable(ctx, r, parquet.NewReaderProperties(memory.DefaultAllocator), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+
+	if err != nil {
+		t.Fatalf("Failed to read table, %v", err)
+	}
+
+	defer tbl.Release()
+
+	if tbl.NumRows() != 5 || tbl.NumCols() != 5 {
+		t.Fatalf("Unexpected number of rows (%d) or columns (%d)", tbl.NumRows(), tbl.NumCols())
+	}
+
+	expected := Schema[float32](3)
+
+	for i, f := range tbl.Schema().Fields() {
+
+		if f.Name != expected.Field(i).Name || !arrow.TypeEqual(f.Type, expected.Field(i).Type) {
+			t.Fatalf("Unexpected field at %d, %v", i, f)
+		}
+	}
+}
+
+func TestWriterDimensions(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	wr, err := NewIPCWriter[float32](&buf, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	err = wr.Write(testingResponses(1)[0])
+
+	if err != nil {
+		t.Fatalf("Failed to write response, %v", err)
+	}
+
+	rsp := &embeddings.CommonEmbeddingsResponse[float32]{
+		CommonEmbeddings: []float32{1, 2},
+	}
+
+	err = wr.Write(rsp)
+
+	if err == nil {
+		t.Fatalf("Expected mismatched dimensions to fail")
+	}
+}
@@ -0,0 +1,61 @@
+package columnar
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/sfomuseum/go-embeddings"
+)
+
+// IPCWriter implements the `Writer` interface for Apache Arrow IPC streams.
+type IPCWriter[T embeddings.Float] struct {
+	Writer[T]
+	batch_writer *batchWriter[T]
+	ipc_writer   *ipc.Writer
+}
+
+// NewIPCWriter returns a new `IPCWriter` instance which writes an Arrow IPC stream to 'wr'.
+func NewIPCWriter[T embeddings.Float](wr io.Writer, opts *WriterOptions) (*IPCWriter[T], error) {
+
+	iw := &IPCWriter[T]{}
+
+	bw := newBatchWriter[T](opts)
+
+	bw.open = func(schema *arrow.Schema) error {
+		iw.ipc_writer = ipc.NewWriter(wr, ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator))
+		return nil
+	}
+
+	bw.write = func(rec arrow.RecordBatch) error {
+		return iw.ipc_writer.Write(rec)
+	}
+
+	iw.batch_writer = bw
+	return iw, nil
+}
+
+// Write adds 'rsp' to the IPC stream.
+func (iw *IPCWriter[T]) Write(rsp embeddings.EmbeddingsResponse[T]) error {
+	return iw.batch_writer.Write(rsp)
+}
+
+// Close writes any buffered responses and the end-of-stream marker.
+func (iw *IPCWriter[T]) Close() error {
+
+	defer iw.batch_writer.release()
+
+	if iw.ipc_writer == nil {
+		return fmt.Errorf("No embeddings were written")
+	}
+
+	err := iw.batch_writer.flush()
+
+	if err != nil {
+		return err
+	}
+
+	return iw.ipc_writer.Close()
+}
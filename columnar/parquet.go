@@ -0,0 +1,86 @@
+package columnar
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/sfomuseum/go-embeddings"
+)
+
+// ParquetWriter implements the `Writer` interface for Apache Parquet files. Each batch of responses is
+// written as a separate row group. Columns are compressed using Zstandard.
+type ParquetWriter[T embeddings.Float] struct {
+	Writer[T]
+	batch_writer *batchWriter[T]
+	file_writer  *pqarrow.FileWriter
+}
+
+// NewParquetWriter returns a new `ParquetWriter` instance which writes to 'wr'. Note that Parquet files are
+// only valid once the `Close` method has been called.
+func NewParquetWriter[T embeddings.Float](wr io.Writer, opts *WriterOptions) (*ParquetWriter[T], error) {
+
+	pw := &ParquetWriter[T]{}
+
+	bw := newBatchWriter[T](opts)
+
+	bw.open = func(schema *arrow.Schema) error {
+
+		props := parquet.NewWriterProperties(
+			parquet.WithCompression(compress.Codecs.Zstd),
+		)
+
+		// Store the Arrow schema so that Arrow readers can recover the fixed-size list type; other readers
+		// (for example DuckDB) will see a list of floats.
+
+		arrow_props := pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema())
+
+		fw, err := pqarrow.NewFileWriter(schema, writerOnly{wr}, props, arrow_props)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create Parquet writer, %w", err)
+		}
+
+		pw.file_writer = fw
+		return nil
+	}
+
+	bw.write = func(rec arrow.RecordBatch) error {
+		return pw.file_writer.Write(rec)
+	}
+
+	pw.batch_writer = bw
+	return pw, nil
+}
+
+// Write adds 'rsp' to the Parquet file.
+func (pw *ParquetWriter[T]) Write(rsp embeddings.EmbeddingsResponse[T]) error {
+	return pw.batch_writer.Write(rsp)
+}
+
+// Close writes any buffered responses and the Parquet file footer.
+func (pw *ParquetWriter[T]) Close() error {
+
+	defer pw.batch_writer.release()
+
+	if pw.file_writer == nil {
+		return fmt.Errorf("No embeddings were written")
+	}
+
+	err := pw.batch_writer.flush()
+
+	if err != nil {
+		return err
+	}
+
+	return pw.file_writer.Close()
+}
+
+// writerOnly hides any `Close` method of the underlying writer since the Parquet file writer
+// will close its destination if it can.
+type writerOnly struct {
+	io.Writer
+}
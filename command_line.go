@@ -0,0 +1,154 @@
+package embeddings
+
+// For talking to the command line (Python) wrappers for non-Go embeddings interfaces.
+//
+// The default protocol is for the command line tool to read a single JSON-encoded `CommandLineEmbeddingsRequest`
+// from STDIN and write a single JSON-encoded `CommandLineEmbeddingsResponse` to STDOUT. Anything the tool
+// writes to STDERR is treated as logging and is included in the error returned if the tool fails. For example:
+//
+//	$> echo '{"modality":"text","body":"SGVsbG8gd29ybGQ="}' | python siglip_cli.py --model_name google/siglip-base-patch16-224 --stdio
+//	{"embeddings": [0.010030805, -0.02573614, ...], "model": "google/siglip-base-patch16-224", "precision": "float32"}
+//
+// The older protocol, where inputs and outputs are exchanged using temporary files, is still available
+// for existing scripts using the "file" protocol.
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// COMMAND_LINE_PROTOCOL_STDIO is the name of the protocol where requests and responses are exchanged, as JSON, over STDIN and STDOUT.
+const COMMAND_LINE_PROTOCOL_STDIO string = "stdio"
+
+// COMMAND_LINE_PROTOCOL_FILE is the name of the (legacy) protocol where inputs and outputs are exchanged using temporary files.
+const COMMAND_LINE_PROTOCOL_FILE string = "file"
+
+// The maximum number of bytes written to STDERR by a command line tool to include in errors.
+const command_line_max_stderr int = 2048
+
+// CommandLineEmbeddingsRequest is the message written to a command line tool's STDIN.
+type CommandLineEmbeddingsRequest struct {
+	// The type of data being embedded. Valid options are "text" or "image".
+	Modality string `json:"modality"`
+	// The (optional) model to use to derive embeddings.
+	Model string `json:"model,omitempty"`
+	// The base64-encoded body of the text or image being embedded.
+	Body string `json:"body"`
+	// The (optional) task the embeddings are for, for example "query" or "document".
+	Task string `json:"task,omitempty"`
+}
+
+// CommandLineEmbeddingsResponse is the message read from a command line tool's STDOUT.
+type CommandLineEmbeddingsResponse struct {
+	Embeddings []float64 `json:"embeddings"`
+	Model      string    `json:"model,omitempty"`
+	Precision  string    `json:"precision,omitempty"`
+	// An optional error message. If present the request is considered to have failed.
+	Error string `json:"error,omitempty"`
+}
+
+func validateCommandLineProtocol(protocol string) error {
+
+	switch protocol {
+	case COMMAND_LINE_PROTOCOL_STDIO, COMMAND_LINE_PROTOCOL_FILE:
+		return nil
+	default:
+		return fmt.Errorf("Invalid or unsupported protocol '%s'", protocol)
+	}
+}
+
+func newCommandLineEmbeddingsRequest(req *EmbeddingsRequest, modality string) *CommandLineEmbeddingsRequest {
+
+	return &CommandLineEmbeddingsRequest{
+		Modality: modality,
+		Model:    req.Model,
+		Body:     base64.StdEncoding.EncodeToString(req.Body),
+		Task:     req.Task,
+	}
+}
+
+// runCommandLineEmbeddings executes 'name' with 'args' writing 'cl_req' to STDIN and decoding STDOUT as a `CommandLineEmbeddingsResponse`.
+func runCommandLineEmbeddings(ctx context.Context, name string, args []string, cl_req *CommandLineEmbeddingsRequest) (*CommandLineEmbeddingsResponse, error) {
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	return runCommandLineEmbeddingsWithCommand(ctx, cmd, cl_req)
+}
+
+// runCommandLineEmbeddingsWithCommand runs 'cmd' writing 'cl_req' to STDIN and decoding STDOUT as a `CommandLineEmbeddingsResponse`.
+func runCommandLineEmbeddingsWithCommand(ctx context.Context, cmd *exec.Cmd, cl_req *CommandLineEmbeddingsRequest) (*CommandLineEmbeddingsResponse, error) {
+
+	enc_req, err := json.Marshal(cl_req)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode request, %w", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(enc_req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	t1 := time.Now()
+
+	err = cmd.Run()
+
+	logClientRequest(ctx, COMMAND_LINE_PROTOCOL_STDIO, cmd.Path, t1, len(enc_req), stdout.Len(), cl_req.Model, err)
+
+	if err != nil {
+		return nil, commandLineError(err, &stderr)
+	}
+
+	var cl_rsp *CommandLineEmbeddingsResponse
+
+	err = json.Unmarshal(stdout.Bytes(), &cl_rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal embeddings, %w", err)
+	}
+
+	if cl_rsp.Error != "" {
+		return nil, fmt.Errorf("Failed to derive embeddings, %s", cl_rsp.Error)
+	}
+
+	return cl_rsp, nil
+}
+
+// runCommandLine executes 'name' with 'args' and includes anything written to STDERR in the error returned if it fails.
+func runCommandLine(ctx context.Context, name string, args []string) error {
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	if err != nil {
+		return commandLineError(err, &stderr)
+	}
+
+	return nil
+}
+
+func commandLineError(err error, stderr *bytes.Buffer) error {
+
+	msg := strings.TrimSpace(stderr.String())
+
+	if msg == "" {
+		return fmt.Errorf("Failed to derive embeddings, %w", err)
+	}
+
+	if len(msg) > command_line_max_stderr {
+		msg = "..." + msg[len(msg)-command_line_max_stderr:]
+	}
+
+	return fmt.Errorf("Failed to derive embeddings, %w (%s)", err, msg)
+}
@@ -0,0 +1,112 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCommandLineScript string = `# go-embeddings-scripts-version: 2
+import base64
+import json
+import sys
+
+req = json.load(sys.stdin)
+body = base64.b64decode(req["body"])
+
+if req["modality"] == "text" and body == b"fail":
+    print("Something went wrong", file=sys.stderr)
+    sys.exit(1)
+
+json.dump({"embeddings": [float(len(body)), 1.0], "model": "test", "precision": "float32"}, sys.stdout)
+`
+
+func TestCommandLineStdioProtocol(t *testing.T) {
+
+	ctx := context.Background()
+
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	script := filepath.Join(t.TempDir(), "cli.py")
+
+	err = os.WriteFile(script, []byte(testCommandLineScript), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write script, %v", err)
+	}
+
+	uri := fmt.Sprintf("siglip://%s?model=test&python=%s", script, python)
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	text_req := &EmbeddingsRequest{
+		Body: []byte("Hello\x00world"),
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, text_req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if rsp.Embeddings()[0] != float32(len(text_req.Body)) {
+		t.Fatalf("Unexpected embeddings: %v", rsp.Embeddings())
+	}
+
+	im_path := "fixtures/1527845303_walrus.jpg"
+
+	im_r, err := os.Open(im_path)
+
+	if err != nil {
+		t.Fatalf("Failed to open %s for reading, %v", im_path, err)
+	}
+
+	defer im_r.Close()
+
+	im_body, err := io.ReadAll(im_r)
+
+	if err != nil {
+		t.Fatalf("Failed to read data from %s, %v", im_path, err)
+	}
+
+	im_req := &EmbeddingsRequest{
+		Body: im_body,
+	}
+
+	rsp, err = emb.ImageEmbeddings(ctx, im_req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive image embeddings, %v", err)
+	}
+
+	if rsp.Embeddings()[0] != float32(len(im_body)) {
+		t.Fatalf("Unexpected image embeddings: %v", rsp.Embeddings())
+	}
+
+	fail_req := &EmbeddingsRequest{
+		Body: []byte("fail"),
+	}
+
+	_, err = emb.TextEmbeddings(ctx, fail_req)
+
+	if err == nil {
+		t.Fatalf("Expected request to fail")
+	}
+
+	if !strings.Contains(err.Error(), "Something went wrong") {
+		t.Fatalf("Expected error to contain STDERR output, %v", err)
+	}
+}
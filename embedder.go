@@ -2,6 +2,7 @@ package embeddings
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"sort"
@@ -16,6 +17,45 @@ type Embedder[T Float] interface {
 	ImageEmbeddings(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error)
 }
 
+// EmbedderCloser is an optional interface for `Embedder` implementations which hold resources (for example
+// subprocesses) that need to be released when the embedder is no longer needed.
+type EmbedderCloser interface {
+	Close(context.Context) error
+}
+
+// CloseEmbedder calls the `Close` method of 'e' if it implements the `EmbedderCloser` interface.
+func CloseEmbedder[T Float](ctx context.Context, e Embedder[T]) error {
+
+	c, ok := e.(EmbedderCloser)
+
+	if !ok {
+		return nil
+	}
+
+	return c.Close(ctx)
+}
+
+// closeEmbedders calls `CloseEmbedder` for each of 'embedders', skipping nil values, and returns any errors joined together.
+func closeEmbedders[T Float](ctx context.Context, embedders []Embedder[T]) error {
+
+	errs := make([]error, 0)
+
+	for _, e := range embedders {
+
+		if e == nil {
+			continue
+		}
+
+		err := CloseEmbedder(ctx, e)
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // EmbedderInitializationFunc is a function defined by individual embedder package and used to create
 // an instance of that embedder
 type EmbedderInitializationFunc[T Float] func(ctx context.Context, uri string) (Embedder[T], error)
@@ -73,6 +113,35 @@ func NewEmbedder32(ctx context.Context, uri string) (Embedder[float32], error) {
 	return NewEmbedder[float32](ctx, uri)
 }
 
+// newEmbedderForPrecision returns a new `Embedder[T]` instance for 'uri' using `NewEmbedder32` or `NewEmbedder64`
+// depending on the type of T. It is used by embedders which wrap other embedders.
+func newEmbedderForPrecision[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	var stub T
+
+	switch any(stub).(type) {
+	case float64:
+
+		cl64, err := NewEmbedder64(ctx, uri)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return any(cl64).(Embedder[T]), nil
+
+	default:
+
+		cl32, err := NewEmbedder32(ctx, uri)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return any(cl32).(Embedder[T]), nil
+	}
+}
+
 func ensureSuffix(uri string, suffix string) (string, error) {
 
 	u, err := url.Parse(uri)
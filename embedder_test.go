@@ -0,0 +1,195 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// testingEmbedder is a deterministic `Embedder` implementation used by tests which need non-empty embeddings.
+// The first element of each embedding is the length of the request body and the remaining elements are their
+// (1-based) index. The `?error=` parameter ("retryable", "ratelimit", "empty" or "fatal") causes requests to fail, or to
+// return neither a response nor an error for "empty"; if `?failures=` is also set only that many requests fail. The `?delay=` parameter (milliseconds) delays each request. The `?reported-model=`
+// parameter overrides the model reported by responses and the `?close-error=` parameter causes Close to fail.
+type testingEmbedder[T Float] struct {
+	Embedder[T]
+	model       string
+	dimensions  int
+	precision   string
+	error_type  string
+	failures    int64
+	delay       time.Duration
+	close_error bool
+	calls       atomic.Int64
+}
+
+// The number of testingEmbedder instances which have been created but not closed, used to check that wrappers
+// close the embedders they create when they fail.
+var testing_open atomic.Int64
+
+func init() {
+	ctx := context.Background()
+	RegisterEmbedder[float32](ctx, "testing", newTestingEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "testing32", newTestingEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "testing64", newTestingEmbedder[float64])
+}
+
+func newTestingEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+
+	dimensions := 4
+
+	if q.Has("dimensions") {
+
+		v, err := strconv.Atoi(q.Get("dimensions"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		dimensions = v
+	}
+
+	model := "testing"
+
+	if q.Has("model") {
+		model = q.Get("model")
+	}
+
+	if q.Has("reported-model") {
+		model = q.Get("reported-model")
+	}
+
+	precision := "float32"
+
+	if strings.HasSuffix(u.Scheme, "64") {
+		precision = "float64"
+	}
+
+	failures := int64(-1)
+
+	if q.Has("failures") {
+
+		v, err := strconv.ParseInt(q.Get("failures"), 10, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		failures = v
+	}
+
+	delay := time.Duration(0)
+
+	if q.Has("delay") {
+
+		v, err := strconv.Atoi(q.Get("delay"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		delay = time.Duration(v) * time.Millisecond
+	}
+
+	e := &testingEmbedder[T]{
+		delay:      delay,
+		model:      model,
+		dimensions: dimensions,
+		precision:  precision,
+		error_type: q.Get("error"),
+		failures:   failures,
+	}
+
+	if q.Has("close-error") {
+
+		v, err := strconv.ParseBool(q.Get("close-error"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		e.close_error = v
+	}
+
+	testing_open.Add(1)
+	return e, nil
+}
+
+func (e *testingEmbedder[T]) Close(ctx context.Context) error {
+
+	testing_open.Add(-1)
+
+	if e.close_error {
+		return fmt.Errorf("Testing close error")
+	}
+
+	return nil
+}
+
+func (e *testingEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req)
+}
+
+func (e *testingEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req)
+}
+
+func (e *testingEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	call := e.calls.Add(1)
+
+	if e.delay > 0 {
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(e.delay):
+		}
+	}
+
+	if e.error_type != "" && (e.failures < 0 || call <= e.failures) {
+
+		switch e.error_type {
+		case "retryable":
+			return nil, &HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}
+		case "ratelimit":
+			return nil, &HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}
+		case "empty":
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("Testing error")
+		}
+	}
+
+	emb := make([]T, e.dimensions)
+
+	for i := 0; i < e.dimensions; i++ {
+		emb[i] = T(i)
+	}
+
+	if e.dimensions > 0 {
+		emb[0] = T(len(req.Body))
+	}
+
+	rsp := &CommonEmbeddingsResponse[T]{
+		CommonId:         req.Id,
+		CommonEmbeddings: emb,
+		CommonModel:      e.model,
+		CommonCreated:    time.Now().Unix(),
+		CommonPrecision:  e.precision,
+	}
+
+	return rsp, nil
+}
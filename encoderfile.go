@@ -8,6 +8,8 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,13 +17,20 @@ import (
 	"github.com/sfomuseum/go-encoderfile/embeddings"
 )
 
+// The encoderfile client reports unsuccessful HTTP responses as untyped errors in the form "{STATUS_CODE} {STATUS}".
+var re_encoderfile_status = regexp.MustCompile(`^(\d{3}) (.*)$`)
+
 // EncoderfileEmbedder implements the `Embedder` interface using an Encoderfile API endpoint to derive embeddings.
 type EncoderfileEmbedder[T Float] struct {
 	Embedder[T]
 
-	client    client.Client
-	precision string
-	normalize bool
+	client     client.Client
+	client_uri string
+	precision  string
+	normalize  bool
+	tasks      *taskOptions
+	// The model declared by the `?model=` parameter, used to match task templates when a request does not specify one.
+	model string
 }
 
 func init() {
@@ -53,6 +62,12 @@ func NewEncoderfileEmbedder[T Float](ctx context.Context, uri string) (Embedder[
 		return nil, err
 	}
 
+	tasks, err := taskOptionsFromQuery(q)
+
+	if err != nil {
+		return nil, err
+	}
+
 	precision := "float32"
 
 	if strings.HasSuffix(u.Scheme, "64") {
@@ -60,9 +75,12 @@ func NewEncoderfileEmbedder[T Float](ctx context.Context, uri string) (Embedder[
 	}
 
 	e := &EncoderfileEmbedder[T]{
-		client:    cl,
-		normalize: true,
-		precision: precision,
+		client:     cl,
+		client_uri: client_uri,
+		normalize:  true,
+		precision:  precision,
+		tasks:      tasks,
+		model:      q.Get("model"),
 	}
 
 	return e, nil
@@ -70,14 +88,30 @@ func NewEncoderfileEmbedder[T Float](ctx context.Context, uri string) (Embedder[
 
 func (e *EncoderfileEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
+	model := req.Model
+
+	if model == "" {
+		model = e.model
+	}
+
+	body, err := e.tasks.apply(req, model)
+
+	if err != nil {
+		return nil, err
+	}
+
 	input := []string{
-		string(req.Body),
+		string(body),
 	}
 
+	t1 := time.Now()
+
 	cl_rsp, err := e.client.Embeddings(ctx, input, e.normalize)
 
+	logClientRequest(ctx, "HTTP", e.client_uri, t1, len(body), -1, model, err)
+
 	if err != nil {
-		return nil, err
+		return nil, encoderfileError(err)
 	}
 
 	pooled, err := embeddings.PoolOutputResults(cl_rsp)
@@ -111,3 +145,18 @@ func (e *EncoderfileEmbedder[T]) TextEmbeddings(ctx context.Context, req *Embedd
 func (e *EncoderfileEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 	return nil, NotImplemented
 }
+
+// encoderfileError returns 'err' as an `HTTPError` if it reports an unsuccessful HTTP response, so that it can be
+// classified by `IsRetryableError`, or 'err' unchanged otherwise.
+func encoderfileError(err error) error {
+
+	m := re_encoderfile_status.FindStringSubmatch(err.Error())
+
+	if m == nil {
+		return err
+	}
+
+	code, _ := strconv.Atoi(m[1])
+
+	return &HTTPError{StatusCode: code, Status: m[2]}
+}
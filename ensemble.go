@@ -0,0 +1,326 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// Combine embeddings by concatenating them, in the order the members are defined.
+	ENSEMBLE_CONCAT string = "concat"
+	// Combine embeddings by averaging them (weighted). All members must return embeddings with the same dimensions.
+	ENSEMBLE_MEAN string = "mean"
+)
+
+// The separator used to join the names of member models in the response of an `EnsembleEmbedder`.
+const ENSEMBLE_MODEL_SEPARATOR string = "+"
+
+// EnsembleMember defines the contribution of an individual embedder to the embeddings returned by an `EnsembleEmbedder`.
+type EnsembleMember struct {
+	// The model reported by the member's response.
+	Model string `json:"model"`
+	// The number of dimensions of the member's embeddings.
+	Dimensions int `json:"dimensions"`
+	// The weight applied to the member's embeddings.
+	Weight float64 `json:"weight"`
+}
+
+// EnsembleEmbeddingsResponse is an `EmbeddingsResponse` implementation which includes details about each of the
+// embedders used to derive the final embeddings.
+type EnsembleEmbeddingsResponse[T Float] struct {
+	CommonEmbeddingsResponse[T]
+	Members []*EnsembleMember `json:"members"`
+}
+
+// EnsembleEmbedder implements the `Embedder` interface by deriving embeddings for the same request using multiple
+// `Embedder` instances, in parallel, and combining the results.
+type EnsembleEmbedder[T Float] struct {
+	Embedder[T]
+	members   []Embedder[T]
+	models    []string
+	weights   []float64
+	method    string
+	normalize bool
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "ensemble", NewEnsembleEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "ensemble32", NewEnsembleEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "ensemble64", NewEnsembleEmbedder[float64])
+}
+
+// NewEnsembleEmbedder creates a new `EnsembleEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	ensemble://?client-uri={CLIENT_URI}&client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of a member `Embedder`. May be repeated. Each URI may be followed by `...` and the name of the model
+// to request from that member; otherwise the model in each request is passed through.
+// * `method` – The method used to combine embeddings. Valid options are "concat" and "mean". Default is "concat".
+// * `weight` – The weight of each member, in the same order as the `client-uri` parameters. If a single value is provided it is applied to all members. Default is 1.0.
+// * `normalize` – A boolean flag indicating whether each member's embeddings should be L2-normalized before they are weighted and combined. Default is true.
+func NewEnsembleEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	client_uris := q["client-uri"]
+
+	if len(client_uris) == 0 {
+		return nil, fmt.Errorf("A minimum of (1) ?client-uri= parameters is required")
+	}
+
+	e := &EnsembleEmbedder[T]{
+		members:   make([]Embedder[T], len(client_uris)),
+		models:    make([]string, len(client_uris)),
+		method:    ENSEMBLE_CONCAT,
+		normalize: true,
+	}
+
+	if q.Has("method") {
+		e.method = q.Get("method")
+	}
+
+	switch e.method {
+	case ENSEMBLE_CONCAT, ENSEMBLE_MEAN:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?method= parameter")
+	}
+
+	weights, err := perBackendFloats(q["weight"], len(client_uris), 1.0)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ?weight= parameter, %w", err)
+	}
+
+	e.weights = weights
+
+	if q.Has("normalize") {
+
+		v, err := strconv.ParseBool(q.Get("normalize"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?normalize= parameter, %w", err)
+		}
+
+		e.normalize = v
+	}
+
+	for idx, spec := range client_uris {
+
+		// Unlike failover:// the model is only used if it is explicitly appended to the URI, since members
+		// are expected to use different models
+
+		client_uri, model, found := strings.Cut(spec, ROUTE_SEPARATOR)
+
+		// Close the members which have already been created so that they are not left running
+
+		if found && model == "" {
+			closeEmbedders(ctx, e.members)
+			return nil, fmt.Errorf("?client-uri= parameter must be in the form of '{CLIENT_URI}' or '{CLIENT_URI}%s{MODEL}'", ROUTE_SEPARATOR)
+		}
+
+		cl, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+		if err != nil {
+			closeEmbedders(ctx, e.members)
+			return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+		}
+
+		e.members[idx] = cl
+		e.models[idx] = model
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives text embeddings for 'req' using each member and combines the results.
+func (e *EnsembleEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, func(cl Embedder[T]) func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+		return cl.TextEmbeddings
+	})
+}
+
+// ImageEmbeddings derives image embeddings for 'req' using each member and combines the results.
+func (e *EnsembleEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, func(cl Embedder[T]) func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+		return cl.ImageEmbeddings
+	})
+}
+
+// Close closes each of the member embedders, returning any errors joined together.
+func (e *EnsembleEmbedder[T]) Close(ctx context.Context) error {
+	return closeEmbedders(ctx, e.members)
+}
+
+func (e *EnsembleEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, method func(Embedder[T]) func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	responses := make([]EmbeddingsResponse[T], len(e.members))
+
+	g, g_ctx := errgroup.WithContext(ctx)
+
+	for idx, cl := range e.members {
+
+		member_req := &EmbeddingsRequest{
+			Id:    req.Id,
+			Model: req.Model,
+			Body:  req.Body,
+			Task:  req.Task,
+		}
+
+		if e.models[idx] != "" {
+			member_req.Model = e.models[idx]
+		}
+
+		fn := method(cl)
+
+		g.Go(func() error {
+
+			rsp, err := fn(g_ctx, member_req)
+
+			if err != nil {
+				return fmt.Errorf("Failed to derive embeddings for member %d, %w", idx, err)
+			}
+
+			responses[idx] = rsp
+			return nil
+		})
+	}
+
+	err := g.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]T, len(responses))
+	members := make([]*EnsembleMember, len(responses))
+	models := make([]string, len(responses))
+
+	for idx, rsp := range responses {
+
+		v := rsp.Embeddings()
+
+		if e.normalize {
+			v = Normalize(v)
+		}
+
+		vectors[idx] = v
+		models[idx] = rsp.Model()
+
+		members[idx] = &EnsembleMember{
+			Model:      rsp.Model(),
+			Dimensions: len(v),
+			Weight:     e.weights[idx],
+		}
+	}
+
+	var combined []T
+
+	switch e.method {
+	case ENSEMBLE_MEAN:
+
+		for idx, v := range vectors {
+
+			if len(v) != len(vectors[0]) {
+				return nil, fmt.Errorf("Member %d returned embeddings with %d dimensions, expected %d", idx, len(v), len(vectors[0]))
+			}
+		}
+
+		combined, err = WeightedMeanPool(vectors, e.weights)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to average embeddings, %w", err)
+		}
+
+	default:
+
+		dims := 0
+
+		for _, v := range vectors {
+			dims += len(v)
+		}
+
+		combined = make([]T, 0, dims)
+
+		for idx, v := range vectors {
+
+			for _, f := range v {
+				combined = append(combined, T(float64(f)*e.weights[idx]))
+			}
+		}
+	}
+
+	rsp := &EnsembleEmbeddingsResponse[T]{
+		CommonEmbeddingsResponse: CommonEmbeddingsResponse[T]{
+			CommonId:         req.Id,
+			CommonEmbeddings: combined,
+			CommonModel:      strings.Join(models, ENSEMBLE_MODEL_SEPARATOR),
+			CommonCreated:    time.Now().Unix(),
+			CommonPrecision:  responses[0].Precision(),
+		},
+		Members: members,
+	}
+
+	return rsp, nil
+}
+
+// perBackendFloats parses 'values' returning one (positive) float for each of 'count' backends. If 'values' is empty 'default_value'
+// is used for all backends; if it contains a single value that value is used for all backends.
+func perBackendFloats(values []string, count int, default_value float64) ([]float64, error) {
+
+	floats := make([]float64, count)
+
+	switch len(values) {
+	case 0:
+
+		for i := range floats {
+			floats[i] = default_value
+		}
+
+		return floats, nil
+
+	case 1, count:
+		// pass
+	default:
+		return nil, fmt.Errorf("Expected 1 or %d values, got %d", count, len(values))
+	}
+
+	for i := range floats {
+
+		str_v := values[0]
+
+		if len(values) == count {
+			str_v = values[i]
+		}
+
+		v, err := strconv.ParseFloat(str_v, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if v <= 0 {
+			return nil, fmt.Errorf("Value must be greater than 0")
+		}
+
+		floats[i] = v
+	}
+
+	return floats, nil
+}
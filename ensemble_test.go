@@ -0,0 +1,176 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEnsembleEmbedderConcat(t *testing.T) {
+
+	ctx := context.Background()
+
+	uri := "ensemble://?client-uri=testing://%3Fmodel=a%26dimensions=4&client-uri=testing://%3Fmodel=b%26dimensions=8&weight=1&weight=2"
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Id:   "1",
+		Body: []byte("Hello world"),
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if len(rsp.Embeddings()) != 12 {
+		t.Fatalf("Expected 12 dimensions, got %d", len(rsp.Embeddings()))
+	}
+
+	if rsp.Model() != "a+b" {
+		t.Fatalf("Unexpected model, %s", rsp.Model())
+	}
+
+	// Each member is normalized and then weighted
+
+	norms := []float64{0, 0}
+
+	for i, f := range rsp.Embeddings() {
+
+		idx := 0
+
+		if i >= 4 {
+			idx = 1
+		}
+
+		norms[idx] += float64(f) * float64(f)
+	}
+
+	if math.Abs(math.Sqrt(norms[0])-1) > 1e-5 || math.Abs(math.Sqrt(norms[1])-2) > 1e-5 {
+		t.Fatalf("Unexpected member norms, %v", norms)
+	}
+
+	ensemble_rsp := rsp.(*EnsembleEmbeddingsResponse[float32])
+
+	if len(ensemble_rsp.Members) != 2 || ensemble_rsp.Members[1].Model != "b" || ensemble_rsp.Members[1].Dimensions != 8 || ensemble_rsp.Members[1].Weight != 2 {
+		t.Fatalf("Unexpected members, %v", ensemble_rsp.Members)
+	}
+}
+
+func TestEnsembleEmbedderMean(t *testing.T) {
+
+	ctx := context.Background()
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	emb, err := NewEmbedder32(ctx, "ensemble://?client-uri=testing://%3Fmodel=a&client-uri=testing://%3Fmodel=b&method=mean&normalize=false")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	rsp, err := emb.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if len(rsp.Embeddings()) != 4 || rsp.Embeddings()[0] != 11 || rsp.Embeddings()[3] != 3 {
+		t.Fatalf("Unexpected embeddings, %v", rsp.Embeddings())
+	}
+
+	// Averaging requires matching dimensions
+
+	emb, err = NewEmbedder32(ctx, "ensemble://?client-uri=testing://%3Fdimensions=4&client-uri=testing://%3Fdimensions=8&method=mean")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected mismatched dimensions to fail")
+	}
+}
+
+func TestEnsembleEmbedderErrors(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "ensemble://?client-uri=testing://&client-uri=testing://%3Ferror=fatal")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected member error to fail request")
+	}
+
+	for _, uri := range []string{
+		"ensemble://",
+		"ensemble://?client-uri=testing://&method=sum",
+		"ensemble://?client-uri=testing://&client-uri=testing://&weight=1&weight=2&weight=3",
+		"ensemble://?client-uri=testing://&weight=0",
+	} {
+
+		_, err := NewEmbedder32(ctx, uri)
+
+		if err == nil {
+			t.Fatalf("Expected %s to fail", uri)
+		}
+	}
+}
+
+func TestEnsembleEmbedderClose(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	// Backends which have already been created are closed if a later backend can not be created
+
+	_, err := NewEmbedder32(ctx, "ensemble://?client-uri=testing://&client-uri=unknown://")
+
+	if err == nil {
+		t.Fatalf("Expected unknown backend to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected backends not to be left open")
+	}
+
+	// Every backend is closed even if closing an earlier one fails
+
+	emb, err := NewEmbedder32(ctx, "ensemble://?client-uri=testing://%3Fclose-error=true&client-uri=testing://%3Fclose-error=true")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	err = CloseEmbedder(ctx, emb)
+
+	if err == nil || strings.Count(err.Error(), "Testing close error") != 2 {
+		t.Fatalf("Expected both close errors, got %v", err)
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected all backends to be closed")
+	}
+}
@@ -1,7 +1,115 @@
 package embeddings
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var NotImplemented = errors.New("Not implemented")
+
+// HTTPError is returned by embedders which talk to HTTP services when a request fails with a non-200 status code.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("Embeddings request failed %d: %s", e.StatusCode, e.Status)
+}
+
+// Retryable reports whether the request may succeed if it is retried, or sent to another backend.
+func (e *HTTPError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// RetryableError is an optional interface for errors which know whether the operation that produced them is worth retrying.
+type RetryableError interface {
+	error
+	Retryable() bool
+}
+
+// IsRetryableError returns a boolean value indicating whether 'err' is likely to be transient (for example a network error,
+// a timeout, an HTTP 5xx response or a gRPC "Unavailable", "ResourceExhausted" or "DeadlineExceeded" status) such that the request may succeed if it is retried or sent to another backend.
+// Cancelled contexts and `NotImplemented` errors are never retryable.
+func IsRetryableError(err error) bool {
+
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, NotImplemented) {
+		return false
+	}
+
+	var r RetryableError
+
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+
+	if s, ok := status.FromError(err); ok {
+
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+			return true
+		default:
+			return false
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var net_err net.Error
+
+	return errors.As(err, &net_err)
+}
+
+// IsRateLimitError returns a boolean value indicating whether 'err' was caused by the backend throttling requests (an HTTP 429
+// response or a gRPC "ResourceExhausted" status).
+func IsRateLimitError(err error) bool {
+
+	var http_err *HTTPError
+
+	if errors.As(err, &http_err) {
+		return http_err.StatusCode == http.StatusTooManyRequests
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.ResourceExhausted
+	}
+
+	return false
+}
+
+// CircuitOpenError is returned by `BreakerEmbedder` when requests are refused without being sent to the underlying embedder
+// because it has failed repeatedly.
+type CircuitOpenError struct {
+	// A label for the underlying embedder, derived from its URI without any query parameters.
+	Label string
+	// The time after which requests will be sent to the underlying embedder again.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("Circuit breaker for %s is open until %s", e.Label, e.Until.Format(time.RFC3339))
+}
+
+// Retryable returns true since the request was never sent and may succeed if it is sent to another backend, or later.
+func (e *CircuitOpenError) Retryable() bool {
+	return true
+}
@@ -0,0 +1,89 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryableError(t *testing.T) {
+
+	tests := map[error]bool{
+		&HTTPError{StatusCode: 503}:                                             true,
+		&HTTPError{StatusCode: 429}:                                             true,
+		&HTTPError{StatusCode: 400}:                                             false,
+		fmt.Errorf("Wrapped, %w", syscall.ECONNREFUSED):                         true,
+		fmt.Errorf("Wrapped, %w", io.ErrUnexpectedEOF):                          true,
+		fmt.Errorf("Wrapped, %w", context.DeadlineExceeded):                     true,
+		fmt.Errorf("Wrapped, %w", context.Canceled):                             false,
+		status.Error(codes.Unavailable, "unavailable"):                          true,
+		status.Error(codes.ResourceExhausted, "busy"):                           true,
+		fmt.Errorf("Wrapped, %w", status.Error(codes.DeadlineExceeded, "slow")): true,
+		status.Error(codes.InvalidArgument, "invalid"):                          false,
+		NotImplemented:              false,
+		fmt.Errorf("Invalid input"): false,
+	}
+
+	for err, expected := range tests {
+
+		if IsRetryableError(err) != expected {
+			t.Fatalf("Unexpected result for '%v', expected %t", err, expected)
+		}
+	}
+}
+
+func TestIsRateLimitError(t *testing.T) {
+
+	tests := map[error]bool{
+		fmt.Errorf("Wrapped, %w", &HTTPError{StatusCode: 429}): true,
+		&HTTPError{StatusCode: 503}:                            false,
+		status.Error(codes.ResourceExhausted, "busy"):          true,
+		status.Error(codes.Unavailable, "unavailable"):         false,
+		fmt.Errorf("Invalid input"):                            false,
+	}
+
+	for err, expected := range tests {
+
+		if IsRateLimitError(err) != expected {
+			t.Fatalf("Unexpected result for '%v', expected %t", err, expected)
+		}
+	}
+}
+
+func TestEncoderfileHTTPError(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		http.Error(rsp, "Busy", http.StatusServiceUnavailable)
+	}))
+
+	defer server.Close()
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "encoderfile://?client-uri="+url.QueryEscape(server.URL))
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, &EmbeddingsRequest{Body: []byte("Hello world")})
+
+	var http_err *HTTPError
+
+	if !errors.As(err, &http_err) || http_err.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected HTTP 503 error, got %v", err)
+	}
+
+	if !IsRetryableError(err) {
+		t.Fatalf("Expected encoderfile 503 error to be retryable")
+	}
+}
@@ -0,0 +1,182 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Item defines a text string or image that is embedded as part of an evaluation.
+type Item struct {
+	// A unique identifier for the item. For documents which define an image and no identifier the image path is used.
+	Id string `json:"id,omitempty"`
+	// The text to embed.
+	Text string `json:"text,omitempty"`
+	// The path of an image to embed. Relative paths are resolved relative to the dataset file.
+	Image string `json:"image,omitempty"`
+}
+
+// Query defines a query and the identifiers of the documents which are relevant to it.
+type Query struct {
+	Item
+	// The identifiers (or image paths) of the documents which are relevant to the query.
+	Relevant []string `json:"relevant"`
+}
+
+// Dataset defines a labeled set of documents and queries used to evaluate retrieval quality.
+type Dataset struct {
+	Documents []*Item  `json:"documents"`
+	Queries   []*Query `json:"queries"`
+	// The directory that relative image paths are resolved against.
+	root string
+}
+
+// ReadDataset reads and validates the JSON-encoded dataset in 'path'.
+func ReadDataset(path string) (*Dataset, error) {
+
+	r, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open dataset, %w", err)
+	}
+
+	defer r.Close()
+
+	ds, err := DecodeDataset(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ds.root = filepath.Dir(path)
+	return ds, nil
+}
+
+// DecodeDataset decodes and validates a JSON-encoded dataset read from 'r'. Relative image paths are resolved
+// against the current working directory.
+func DecodeDataset(r io.Reader) (*Dataset, error) {
+
+	var ds *Dataset
+
+	dec := json.NewDecoder(r)
+	err := dec.Decode(&ds)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode dataset, %w", err)
+	}
+
+	if ds == nil {
+		return nil, fmt.Errorf("Missing dataset")
+	}
+
+	err = ds.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// Validate assigns identifiers to image documents without one and returns an error if the dataset is empty,
+// if any document or query is null, if any item does not define exactly one of text or image, if document identifiers are not unique or if a query
+// has no relevant documents or references a document that does not exist.
+func (ds *Dataset) Validate() error {
+
+	if len(ds.Documents) == 0 {
+		return fmt.Errorf("Dataset has no documents")
+	}
+
+	if len(ds.Queries) == 0 {
+		return fmt.Errorf("Dataset has no queries")
+	}
+
+	seen := make(map[string]bool)
+
+	for idx, doc := range ds.Documents {
+
+		if doc == nil {
+			return fmt.Errorf("Document at offset %d is empty", idx)
+		}
+
+		if doc.Id == "" {
+			doc.Id = doc.Image
+		}
+
+		if doc.Id == "" {
+			return fmt.Errorf("Document at offset %d is missing an id", idx)
+		}
+
+		err := doc.validate()
+
+		if err != nil {
+			return fmt.Errorf("Invalid document %s, %w", doc.Id, err)
+		}
+
+		if seen[doc.Id] {
+			return fmt.Errorf("Duplicate document id %s", doc.Id)
+		}
+
+		seen[doc.Id] = true
+	}
+
+	for idx, q := range ds.Queries {
+
+		if q == nil {
+			return fmt.Errorf("Query at offset %d is empty", idx)
+		}
+
+		if q.Id == "" {
+			q.Id = fmt.Sprintf("query#%d", idx)
+		}
+
+		err := q.validate()
+
+		if err != nil {
+			return fmt.Errorf("Invalid query %s, %w", q.Id, err)
+		}
+
+		if len(q.Relevant) == 0 {
+			return fmt.Errorf("Query %s has no relevant documents", q.Id)
+		}
+
+		for _, id := range q.Relevant {
+
+			if !seen[id] {
+				return fmt.Errorf("Query %s references unknown document %s", q.Id, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i *Item) validate() error {
+
+	switch {
+	case i.Text != "" && i.Image != "":
+		return fmt.Errorf("Text and image are mutually exclusive")
+	case i.Text == "" && i.Image == "":
+		return fmt.Errorf("Missing text or image")
+	default:
+		return nil
+	}
+}
+
+// body returns the bytes to embed for 'i', reading image paths relative to 'root'.
+func (i *Item) body(root string) ([]byte, error) {
+
+	if i.Image == "" {
+		return []byte(i.Text), nil
+	}
+
+	path := i.Image
+
+	if !filepath.IsAbs(path) && root != "" {
+		path = filepath.Join(root, path)
+	}
+
+	return os.ReadFile(path)
+}
@@ -0,0 +1,78 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadDataset(t *testing.T) {
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, "walrus.jpg"), []byte("not really a walrus"), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write image, %v", err)
+	}
+
+	path := filepath.Join(dir, "dataset.json")
+
+	err = os.WriteFile(path, []byte(`{
+"documents": [ { "image": "walrus.jpg" }, { "id": "d2", "text": "An airplane" } ],
+"queries": [ { "text": "A walrus", "relevant": [ "walrus.jpg" ] } ]
+}`), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write dataset, %v", err)
+	}
+
+	ds, err := ReadDataset(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read dataset, %v", err)
+	}
+
+	if ds.Documents[0].Id != "walrus.jpg" {
+		t.Fatalf("Unexpected document id, %s", ds.Documents[0].Id)
+	}
+
+	if ds.Queries[0].Id != "query#0" {
+		t.Fatalf("Unexpected query id, %s", ds.Queries[0].Id)
+	}
+
+	body, err := ds.Documents[0].body(ds.root)
+
+	if err != nil {
+		t.Fatalf("Failed to read image relative to dataset, %v", err)
+	}
+
+	if string(body) != "not really a walrus" {
+		t.Fatalf("Unexpected body, %s", string(body))
+	}
+}
+
+func TestDatasetValidate(t *testing.T) {
+
+	tests := map[string]string{
+		"no documents":     `{ "queries": [ { "text": "a", "relevant": [ "d1" ] } ] }`,
+		"no queries":       `{ "documents": [ { "id": "d1", "text": "a" } ] }`,
+		"duplicate id":     `{ "documents": [ { "id": "d1", "text": "a" }, { "id": "d1", "text": "b" } ], "queries": [ { "text": "a", "relevant": [ "d1" ] } ] }`,
+		"text and image":   `{ "documents": [ { "id": "d1", "text": "a", "image": "a.jpg" } ], "queries": [ { "text": "a", "relevant": [ "d1" ] } ] }`,
+		"missing relevant": `{ "documents": [ { "id": "d1", "text": "a" } ], "queries": [ { "text": "a" } ] }`,
+		"unknown relevant": `{ "documents": [ { "id": "d1", "text": "a" } ], "queries": [ { "text": "a", "relevant": [ "d2" ] } ] }`,
+		"null dataset":     `null`,
+		"null document":    `{ "documents": [ null ], "queries": [ { "text": "a", "relevant": [ "d1" ] } ] }`,
+		"null query":       `{ "documents": [ { "id": "d1", "text": "a" } ], "queries": [ null ] }`,
+	}
+
+	for name, enc := range tests {
+
+		_, err := DecodeDataset(strings.NewReader(enc))
+
+		if err == nil {
+			t.Fatalf("Expected dataset with %s to fail validation", name)
+		}
+	}
+}
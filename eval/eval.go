@@ -0,0 +1,235 @@
+// Package eval provides methods for measuring the retrieval quality and latency of `Embedder` implementations
+// using a labeled set of queries and documents.
+package eval
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sfomuseum/go-embeddings"
+	"github.com/sfomuseum/go-embeddings/internal/latency"
+)
+
+// The default cut-offs used to calculate recall and nDCG.
+var DefaultK = []int{1, 5, 10}
+
+// Options defines configuration options for evaluating an `Embedder`.
+type Options struct {
+	// A label identifying the embedder being evaluated, for example its URI.
+	Label string
+	// The cut-offs used to calculate recall and nDCG. If empty `DefaultK` is used.
+	K []int
+	// An optional model to assign to each embeddings request.
+	Model string
+}
+
+// Latency defines summary statistics, in milliseconds, for the time taken to derive individual embeddings.
+type Latency struct {
+	// The number of embeddings derived.
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P95   float64 `json:"p95_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+// Result defines the retrieval quality and latency of an `Embedder` for a `Dataset`.
+type Result struct {
+	// The label assigned by `Options.Label`.
+	Embedder string `json:"embedder"`
+	// The model reported by the embedder for the first document.
+	Model string `json:"model,omitempty"`
+	// The dimensions of the embeddings.
+	Dimensions int32 `json:"dimensions"`
+	// The number of queries evaluated.
+	Queries int `json:"queries"`
+	// The number of documents ranked.
+	Documents int `json:"documents"`
+	// The mean recall at each cut-off.
+	Recall map[int]float64 `json:"recall"`
+	// The mean reciprocal rank of the first relevant document.
+	MRR float64 `json:"mrr"`
+	// The mean normalized discounted cumulative gain at each cut-off.
+	NDCG map[int]float64 `json:"ndcg"`
+	// Latency statistics for every embeddings request (documents and queries).
+	Latency Latency `json:"latency"`
+}
+
+// Evaluate derives embeddings for every document and query in 'ds' using 'cl', ranks the documents for each query
+// by cosine similarity and returns the mean recall, reciprocal rank and nDCG across all queries. Queries are embedded
+// with the "query" task and documents with the "document" task.
+func Evaluate[T embeddings.Float](ctx context.Context, cl embeddings.Embedder[T], ds *Dataset, opts *Options) (*Result, error) {
+
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	k_values := opts.K
+
+	if len(k_values) == 0 {
+		k_values = DefaultK
+	}
+
+	for _, k := range k_values {
+
+		if k < 1 {
+			return nil, fmt.Errorf("Invalid k value, %d", k)
+		}
+	}
+
+	result := &Result{
+		Embedder:  opts.Label,
+		Queries:   len(ds.Queries),
+		Documents: len(ds.Documents),
+		Recall:    make(map[int]float64),
+		NDCG:      make(map[int]float64),
+	}
+
+	durations := make([]time.Duration, 0, len(ds.Documents)+len(ds.Queries))
+
+	embed := func(item *Item, task string) ([]T, error) {
+
+		body, err := item.body(ds.root)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s, %w", item.Id, err)
+		}
+
+		req := &embeddings.EmbeddingsRequest{
+			Id:    item.Id,
+			Model: opts.Model,
+			Body:  body,
+			Task:  task,
+		}
+
+		var rsp embeddings.EmbeddingsResponse[T]
+
+		t1 := time.Now()
+
+		if item.Image != "" {
+			rsp, err = cl.ImageEmbeddings(ctx, req)
+		} else {
+			rsp, err = cl.TextEmbeddings(ctx, req)
+		}
+
+		durations = append(durations, time.Since(t1))
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive embeddings for %s, %w", item.Id, err)
+		}
+
+		if result.Dimensions == 0 {
+			result.Model = rsp.Model()
+			result.Dimensions = rsp.Dimensions()
+		}
+
+		return rsp.Embeddings(), nil
+	}
+
+	doc_embeddings := make([][]T, len(ds.Documents))
+
+	for idx, doc := range ds.Documents {
+
+		v, err := embed(doc, embeddings.TASK_DOCUMENT)
+
+		if err != nil {
+			return nil, err
+		}
+
+		doc_embeddings[idx] = v
+	}
+
+	type scored struct {
+		id    string
+		score float64
+	}
+
+	for _, q := range ds.Queries {
+
+		v, err := embed(&q.Item, embeddings.TASK_QUERY)
+
+		if err != nil {
+			return nil, err
+		}
+
+		scores := make([]scored, len(ds.Documents))
+
+		for idx, doc := range ds.Documents {
+
+			sim, err := embeddings.CosineSimilarity(v, doc_embeddings[idx])
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to compare query %s with document %s, %w", q.Id, doc.Id, err)
+			}
+
+			scores[idx] = scored{id: doc.Id, score: sim}
+		}
+
+		// Stable, so that ties are ranked in dataset order
+
+		slices.SortStableFunc(scores, func(a scored, b scored) int {
+			return cmp.Compare(b.score, a.score)
+		})
+
+		ranked := make([]string, len(scores))
+
+		for idx, s := range scores {
+			ranked[idx] = s.id
+		}
+
+		relevant := make(map[string]bool)
+
+		for _, id := range q.Relevant {
+			relevant[id] = true
+		}
+
+		for _, k := range k_values {
+			result.Recall[k] += Recall(ranked, relevant, k)
+			result.NDCG[k] += NDCG(ranked, relevant, k)
+		}
+
+		result.MRR += ReciprocalRank(ranked, relevant)
+	}
+
+	count := float64(len(ds.Queries))
+
+	for _, k := range k_values {
+		result.Recall[k] /= count
+		result.NDCG[k] /= count
+	}
+
+	result.MRR /= count
+	result.Latency = latencyStats(durations)
+
+	return result, nil
+}
+
+func latencyStats(durations []time.Duration) Latency {
+
+	l := Latency{
+		Count: len(durations),
+	}
+
+	if len(durations) == 0 {
+		return l
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+
+	for _, d := range sorted {
+		total += d
+	}
+
+	l.Mean = latency.Milliseconds(total / time.Duration(len(sorted)))
+	l.P50 = latency.Milliseconds(latency.Percentile(sorted, 0.50))
+	l.P95 = latency.Milliseconds(latency.Percentile(sorted, 0.95))
+	l.Max = latency.Milliseconds(sorted[len(sorted)-1])
+
+	return l
+}
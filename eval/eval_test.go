@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sfomuseum/go-embeddings"
+)
+
+// keywordEmbedder derives embeddings counting the occurrences of a fixed set of keywords.
+type keywordEmbedder struct {
+	keywords []string
+}
+
+func (e *keywordEmbedder) TextEmbeddings(ctx context.Context, req *embeddings.EmbeddingsRequest) (embeddings.EmbeddingsResponse[float32], error) {
+
+	v := make([]float32, len(e.keywords))
+
+	for idx, kw := range e.keywords {
+		v[idx] = float32(strings.Count(string(req.Body), kw))
+	}
+
+	rsp := &embeddings.CommonEmbeddingsResponse[float32]{
+		CommonId:         req.Id,
+		CommonEmbeddings: v,
+		CommonModel:      "keywords",
+	}
+
+	return rsp, nil
+}
+
+func (e *keywordEmbedder) ImageEmbeddings(ctx context.Context, req *embeddings.EmbeddingsRequest) (embeddings.EmbeddingsResponse[float32], error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func TestEvaluate(t *testing.T) {
+
+	ctx := context.Background()
+
+	ds, err := DecodeDataset(strings.NewReader(`{
+"documents": [
+	{ "id": "walrus", "text": "walrus walrus" },
+	{ "id": "plane", "text": "plane" },
+	{ "id": "both", "text": "walrus plane plane" }
+],
+"queries": [
+	{ "id": "q1", "text": "walrus", "relevant": [ "walrus" ] },
+	{ "id": "q2", "text": "walrus", "relevant": [ "both" ] }
+]
+}`))
+
+	if err != nil {
+		t.Fatalf("Failed to decode dataset, %v", err)
+	}
+
+	cl := &keywordEmbedder{
+		keywords: []string{"walrus", "plane"},
+	}
+
+	opts := &Options{
+		Label: "keywords://",
+		K:     []int{1, 3},
+	}
+
+	r, err := Evaluate[float32](ctx, cl, ds, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to evaluate, %v", err)
+	}
+
+	// Both queries rank "walrus" first and "both" second
+
+	if r.Recall[1] != 0.5 || r.Recall[3] != 1 {
+		t.Fatalf("Unexpected recall, %v", r.Recall)
+	}
+
+	if r.MRR != 0.75 {
+		t.Fatalf("Unexpected MRR, %f", r.MRR)
+	}
+
+	if r.Model != "keywords" || r.Dimensions != 2 || r.Queries != 2 || r.Documents != 3 {
+		t.Fatalf("Unexpected result, %+v", r)
+	}
+
+	if r.Latency.Count != 5 {
+		t.Fatalf("Unexpected latency count, %d", r.Latency.Count)
+	}
+
+	var buf bytes.Buffer
+
+	err = WriteTable(&buf, []*Result{r})
+
+	if err != nil {
+		t.Fatalf("Failed to write table, %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 2 || !strings.Contains(lines[0], "NDCG@3") || !strings.HasPrefix(lines[1], "keywords://") {
+		t.Fatalf("Unexpected table, %s", buf.String())
+	}
+
+	_, err = Evaluate[float32](ctx, cl, ds, &Options{K: []int{0}})
+
+	if err == nil {
+		t.Fatalf("Expected k=0 to fail")
+	}
+}
@@ -0,0 +1,72 @@
+package eval
+
+import (
+	"math"
+)
+
+// Recall returns the fraction of the documents in 'relevant' which appear in the first 'k' identifiers in 'ranked'.
+func Recall(ranked []string, relevant map[string]bool, k int) float64 {
+
+	if len(relevant) == 0 {
+		return 0
+	}
+
+	found := 0
+
+	for _, id := range truncate(ranked, k) {
+
+		if relevant[id] {
+			found += 1
+		}
+	}
+
+	return float64(found) / float64(len(relevant))
+}
+
+// ReciprocalRank returns the reciprocal of the (1-based) position of the first document in 'ranked' which is
+// also in 'relevant', or zero if there is none.
+func ReciprocalRank(ranked []string, relevant map[string]bool) float64 {
+
+	for idx, id := range ranked {
+
+		if relevant[id] {
+			return 1.0 / float64(idx+1)
+		}
+	}
+
+	return 0
+}
+
+// NDCG returns the normalized discounted cumulative gain, using binary relevance, of the first 'k' identifiers in 'ranked'.
+func NDCG(ranked []string, relevant map[string]bool, k int) float64 {
+
+	if len(relevant) == 0 {
+		return 0
+	}
+
+	dcg := 0.0
+
+	for idx, id := range truncate(ranked, k) {
+
+		if relevant[id] {
+			dcg += 1.0 / math.Log2(float64(idx+2))
+		}
+	}
+
+	ideal := 0.0
+
+	for idx := range min(k, len(relevant)) {
+		ideal += 1.0 / math.Log2(float64(idx+2))
+	}
+
+	return dcg / ideal
+}
+
+func truncate(ranked []string, k int) []string {
+
+	if k < len(ranked) {
+		return ranked[:k]
+	}
+
+	return ranked
+}
@@ -0,0 +1,38 @@
+package eval
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+
+	ranked := []string{"a", "b", "c", "d"}
+
+	relevant := map[string]bool{
+		"b": true,
+		"d": true,
+	}
+
+	tests := []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"recall@1", Recall(ranked, relevant, 1), 0},
+		{"recall@2", Recall(ranked, relevant, 2), 0.5},
+		{"recall@10", Recall(ranked, relevant, 10), 1},
+		{"rr", ReciprocalRank(ranked, relevant), 0.5},
+		{"rr (none)", ReciprocalRank(ranked, map[string]bool{"z": true}), 0},
+		{"ndcg@1", NDCG(ranked, relevant, 1), 0},
+		{"ndcg@4", NDCG(ranked, relevant, 4), (1/math.Log2(3) + 1/math.Log2(5)) / (1 + 1/math.Log2(3))},
+		{"ndcg@2 (ideal)", NDCG([]string{"b", "d", "a"}, relevant, 2), 1},
+	}
+
+	for _, test := range tests {
+
+		if math.Abs(test.value-test.expected) > 1e-9 {
+			t.Fatalf("Unexpected value for %s, expected %f but got %f", test.name, test.expected, test.value)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteTable writes 'results' to 'wr' as a plain-text table, with one row for each embedder, for comparison.
+func WriteTable(wr io.Writer, results []*Result) error {
+
+	k_values := make([]int, 0)
+
+	for _, r := range results {
+
+		for k := range r.Recall {
+
+			if !slices.Contains(k_values, k) {
+				k_values = append(k_values, k)
+			}
+		}
+	}
+
+	slices.Sort(k_values)
+
+	tw := tabwriter.NewWriter(wr, 0, 4, 2, ' ', 0)
+
+	header := []string{"EMBEDDER", "MODEL", "DIMS"}
+
+	for _, k := range k_values {
+		header = append(header, fmt.Sprintf("R@%d", k))
+	}
+
+	header = append(header, "MRR")
+
+	for _, k := range k_values {
+		header = append(header, fmt.Sprintf("NDCG@%d", k))
+	}
+
+	header = append(header, "P50(ms)", "P95(ms)")
+
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, r := range results {
+
+		row := []string{r.Embedder, r.Model, fmt.Sprintf("%d", r.Dimensions)}
+
+		for _, k := range k_values {
+			row = append(row, fmt.Sprintf("%.3f", r.Recall[k]))
+		}
+
+		row = append(row, fmt.Sprintf("%.3f", r.MRR))
+
+		for _, k := range k_values {
+			row = append(row, fmt.Sprintf("%.3f", r.NDCG[k]))
+		}
+
+		row = append(row, fmt.Sprintf("%.1f", r.Latency.P50), fmt.Sprintf("%.1f", r.Latency.P95))
+
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
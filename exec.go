@@ -0,0 +1,453 @@
+package embeddings
+
+// Derive embeddings from any local program which implements the command line protocol described
+// in command_line.go.
+//
+// go run cmd/embeddings/main.go -client-uri 'exec:///usr/local/bin/my-embeddings?arg=--model&arg={model}&model=example' text hello world
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExecEmbedder implements the `Embedder` interface by running an arbitrary local program which reads
+// a JSON-encoded `CommandLineEmbeddingsRequest` from STDIN and writes a JSON-encoded `CommandLineEmbeddingsResponse`
+// to STDOUT. In persistent mode the program is started once and is expected to read (and write) one
+// newline-delimited JSON message per request.
+type ExecEmbedder[T Float] struct {
+	Embedder[T]
+	command    string
+	args       []string
+	env        []string
+	isolate    bool
+	model      string
+	precision  string
+	timeout    time.Duration
+	persistent bool
+	mu         *sync.Mutex
+	process    *execProcess
+}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *execStderr
+	exited chan struct{}
+}
+
+// execStderr retains the most recent output written to STDERR by a persistent process.
+type execStderr struct {
+	mu  *sync.Mutex
+	buf []byte
+}
+
+func init() {
+	ctx := context.Background()
+	RegisterEmbedder[float32](ctx, "exec", NewExecEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "exec32", NewExecEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "exec64", NewExecEmbedder[float64])
+}
+
+// NewExecEmbedder creates a new `ExecEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	exec://{PATH_TO_PROGRAM}?{PARAMETERS}
+//
+// Valid parameters are:
+// * `arg` – Zero or more arguments to pass to the program. The strings "{model}" and "{modality}" will be replaced by their request-specific values.
+// * `env` – Zero or more environment variables to pass to the program, either as "NAME" (passed through from the current environment) or "NAME=VALUE". If present the program will not inherit any other environment variables.
+// * `model` – The default model to use if one is not specified by a request.
+// * `timeout` – The maximum number of seconds to wait for each request to complete. Default is no timeout.
+// * `persistent` – A boolean flag indicating the program should be started once and sent newline-delimited requests. Default is false.
+func NewExecEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	command := filepath.Join(u.Host, u.Path)
+
+	if command == "" {
+		return nil, fmt.Errorf("Missing program path")
+	}
+
+	// Allow programs to be found in the current PATH, e.g. exec://my-embeddings
+
+	if u.Host != "" && u.Path == "" {
+
+		p, err := exec.LookPath(u.Host)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to locate %s, %w", u.Host, err)
+		}
+
+		command = p
+	}
+
+	// Use a non-nil slice so that, if ?env= is present but none of the named variables are set, the program
+	// is still run with an empty environment rather than inheriting the current one.
+
+	env := make([]string, 0)
+
+	for _, e := range q["env"] {
+
+		if strings.Contains(e, "=") {
+			env = append(env, e)
+			continue
+		}
+
+		v, ok := os.LookupEnv(e)
+
+		if ok {
+			env = append(env, fmt.Sprintf("%s=%s", e, v))
+		}
+	}
+
+	var timeout time.Duration
+
+	if q.Has("timeout") {
+
+		v, err := strconv.Atoi(q.Get("timeout"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?timeout= parameter, %w", err)
+		}
+
+		timeout = time.Duration(v) * time.Second
+	}
+
+	persistent := false
+
+	if q.Has("persistent") {
+
+		v, err := strconv.ParseBool(q.Get("persistent"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?persistent= parameter, %w", err)
+		}
+
+		persistent = v
+	}
+
+	precision := "float32"
+
+	if strings.HasSuffix(u.Scheme, "64") {
+		precision = "float64"
+	}
+
+	e := &ExecEmbedder[T]{
+		command:    command,
+		args:       q["arg"],
+		env:        env,
+		isolate:    q.Has("env"),
+		model:      q.Get("model"),
+		precision:  precision,
+		timeout:    timeout,
+		persistent: persistent,
+		mu:         new(sync.Mutex),
+	}
+
+	return e, nil
+}
+
+func (e *ExecEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "text")
+}
+
+func (e *ExecEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "image")
+}
+
+// Close stops the persistent process associated with 'e', if present.
+func (e *ExecEmbedder[T]) Close(ctx context.Context) error {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.stopProcess()
+	return nil
+}
+
+func (e *ExecEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, modality string) (EmbeddingsResponse[T], error) {
+
+	if e.timeout > 0 {
+		timeout_ctx, cancel := context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+		ctx = timeout_ctx
+	}
+
+	cl_req := newCommandLineEmbeddingsRequest(req, modality)
+
+	if cl_req.Model == "" {
+		cl_req.Model = e.model
+	}
+
+	var cl_rsp *CommandLineEmbeddingsResponse
+	var err error
+
+	if e.persistent {
+		cl_rsp, err = e.persistentEmbeddings(ctx, cl_req)
+	} else {
+
+		cmd := exec.CommandContext(ctx, e.command, e.commandArgs(cl_req)...)
+
+		if e.isolate {
+			cmd.Env = e.env
+		}
+
+		cl_rsp, err = runCommandLineEmbeddingsWithCommand(ctx, cmd, cl_req)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	model := cl_rsp.Model
+
+	if model == "" {
+		model = cl_req.Model
+	}
+
+	src_precision := cl_rsp.Precision
+
+	if src_precision == "" {
+		src_precision = "float64"
+	}
+
+	precision := src_precision
+
+	if src_precision != e.precision {
+		precision = fmt.Sprintf("%s#as-%s", src_precision, e.precision)
+	}
+
+	now := time.Now()
+	ts := now.Unix()
+
+	rsp := &CommonEmbeddingsResponse[T]{
+		CommonId:         req.Id,
+		CommonModel:      model,
+		CommonCreated:    ts,
+		CommonPrecision:  precision,
+		CommonEmbeddings: toFloat64Slice[T](cl_rsp.Embeddings),
+	}
+
+	return rsp, nil
+}
+
+// commandArgs returns the list of arguments to pass to the program with any templated values applied.
+func (e *ExecEmbedder[T]) commandArgs(cl_req *CommandLineEmbeddingsRequest) []string {
+
+	r := strings.NewReplacer(
+		"{model}", cl_req.Model,
+		"{modality}", cl_req.Modality,
+	)
+
+	args := make([]string, len(e.args))
+
+	for idx, a := range e.args {
+		args[idx] = r.Replace(a)
+	}
+
+	return args
+}
+
+func (e *ExecEmbedder[T]) persistentEmbeddings(ctx context.Context, cl_req *CommandLineEmbeddingsRequest) (*CommandLineEmbeddingsResponse, error) {
+
+	enc_req, err := json.Marshal(cl_req)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode request, %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	p, err := e.ensureProcess()
+
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		line []byte
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	t1 := time.Now()
+
+	go func() {
+
+		_, err := p.stdin.Write(append(enc_req, '\n'))
+
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+
+		ln, err := p.stdout.ReadBytes('\n')
+		done <- result{line: ln, err: err}
+	}()
+
+	var res result
+
+	select {
+	case <-ctx.Done():
+		// The process can no longer be trusted to be in sync with us so stop it; it will be restarted on the next request
+		e.stopProcess()
+		logClientRequest(ctx, COMMAND_LINE_PROTOCOL_STDIO, e.command, t1, len(enc_req)+1, -1, cl_req.Model, ctx.Err())
+		return nil, fmt.Errorf("Failed to derive embeddings, %w", ctx.Err())
+	case res = <-done:
+	}
+
+	logClientRequest(ctx, COMMAND_LINE_PROTOCOL_STDIO, e.command, t1, len(enc_req)+1, len(res.line), cl_req.Model, res.err)
+
+	if res.err != nil {
+		stderr := p.stderr.Buffer()
+		e.stopProcess()
+		return nil, commandLineError(res.err, stderr)
+	}
+
+	var cl_rsp *CommandLineEmbeddingsResponse
+
+	err = json.Unmarshal(res.line, &cl_rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal embeddings, %w", err)
+	}
+
+	if cl_rsp.Error != "" {
+		return nil, fmt.Errorf("Failed to derive embeddings, %s", cl_rsp.Error)
+	}
+
+	return cl_rsp, nil
+}
+
+// ensureProcess starts the persistent process if it is not already running. It assumes e.mu is locked.
+func (e *ExecEmbedder[T]) ensureProcess() (*execProcess, error) {
+
+	if e.process != nil {
+
+		select {
+		case <-e.process.exited:
+			e.process = nil
+		default:
+			return e.process, nil
+		}
+	}
+
+	// Persistent processes outlive any one request so they are not bound to a context and
+	// only the request-independent "{model}" template (the default model) is applied.
+
+	tmpl_req := &CommandLineEmbeddingsRequest{
+		Model: e.model,
+	}
+
+	cmd := exec.Command(e.command, e.commandArgs(tmpl_req)...)
+
+	if e.isolate {
+		cmd.Env = e.env
+	}
+
+	stdin, err := cmd.StdinPipe()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create stdin pipe, %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create stdout pipe, %w", err)
+	}
+
+	stderr := &execStderr{
+		mu: new(sync.Mutex),
+	}
+
+	cmd.Stderr = stderr
+
+	err = cmd.Start()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to start %s, %w", e.command, err)
+	}
+
+	p := &execProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReaderSize(stdout, 1024*1024),
+		stderr: stderr,
+		exited: make(chan struct{}),
+	}
+
+	go func() {
+		cmd.Wait()
+		close(p.exited)
+	}()
+
+	e.process = p
+	return p, nil
+}
+
+// stopProcess stops the persistent process, if present. It assumes e.mu is locked.
+func (e *ExecEmbedder[T]) stopProcess() {
+
+	if e.process == nil {
+		return
+	}
+
+	p := e.process
+	e.process = nil
+
+	p.stdin.Close()
+
+	select {
+	case <-p.exited:
+		return
+	case <-time.After(time.Second):
+	}
+
+	p.cmd.Process.Kill()
+	<-p.exited
+}
+
+func (s *execStderr) Write(b []byte) (int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, b...)
+
+	if len(s.buf) > command_line_max_stderr {
+		s.buf = s.buf[len(s.buf)-command_line_max_stderr:]
+	}
+
+	return len(b), nil
+}
+
+func (s *execStderr) Buffer() *bytes.Buffer {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return bytes.NewBuffer(append([]byte(nil), s.buf...))
+}
@@ -0,0 +1,201 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testExecScript string = `
+import base64
+import json
+import os
+import sys
+
+def embeddings(req, count):
+    body = base64.b64decode(req["body"])
+    return {"embeddings": [float(len(body)), float(count)], "model": sys.argv[1] + "#" + os.environ.get("EXEC_TEST", ""), "precision": "float32"}
+
+if "--persistent" in sys.argv:
+    count = 0
+    for ln in sys.stdin:
+        count += 1
+        sys.stdout.write(json.dumps(embeddings(json.loads(ln), count)) + "\n")
+        sys.stdout.flush()
+else:
+    json.dump(embeddings(json.load(sys.stdin), 1), sys.stdout)
+`
+
+func TestExecEmbeddings(t *testing.T) {
+
+	ctx := context.Background()
+
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	script := filepath.Join(t.TempDir(), "exec.py")
+
+	err = os.WriteFile(script, []byte(testExecScript), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write script, %v", err)
+	}
+
+	t.Setenv("EXEC_TEST", "ok")
+
+	uri := fmt.Sprintf("exec://%s?arg=%s&arg={model}&model=example&env=EXEC_TEST&timeout=30", python, script)
+
+	emb, err := NewEmbedder64(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	for i := 0; i < 2; i++ {
+
+		rsp, err := emb.TextEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive embeddings, %v", err)
+		}
+
+		if rsp.Model() != "example#ok" {
+			t.Fatalf("Unexpected model '%s'", rsp.Model())
+		}
+
+		if rsp.Precision() != "float32#as-float64" {
+			t.Fatalf("Unexpected precision '%s'", rsp.Precision())
+		}
+
+		e := rsp.Embeddings()
+
+		if e[0] != float64(len(req.Body)) || e[1] != 1 {
+			t.Fatalf("Unexpected embeddings %v", e)
+		}
+	}
+}
+
+func TestExecPersistentEmbeddings(t *testing.T) {
+
+	ctx := context.Background()
+
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	script := filepath.Join(t.TempDir(), "exec.py")
+
+	err = os.WriteFile(script, []byte(testExecScript), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write script, %v", err)
+	}
+
+	uri := fmt.Sprintf("exec://%s?arg=%s&arg={model}&arg=--persistent&model=example&persistent=true", python, script)
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	defer CloseEmbedder(ctx, emb)
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	for i := 1; i <= 3; i++ {
+
+		rsp, err := emb.ImageEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive embeddings, %v", err)
+		}
+
+		e := rsp.Embeddings()
+
+		if e[1] != float32(i) {
+			t.Fatalf("Expected request %d to be handled by the same process, got %v", i, e)
+		}
+	}
+}
+
+func TestExecEmbeddingsEmptyEnvironment(t *testing.T) {
+
+	ctx := context.Background()
+
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	// python3 may be a wrapper script (for example a pyenv shim) which sets its own environment variables
+
+	out, err := exec.Command(python, "-c", "import sys; print(sys.executable)").Output()
+
+	if err != nil {
+		t.Fatalf("Failed to resolve python3 executable, %v", err)
+	}
+
+	python = strings.TrimSpace(string(out))
+
+	// Python may coerce the C locale by setting LC_CTYPE itself so ignore it
+
+	script_body := `
+import json
+import os
+import sys
+
+json.load(sys.stdin)
+names = sorted(k for k in os.environ if k != "LC_CTYPE")
+json.dump({"embeddings": [float(len(names))], "model": ",".join(names), "precision": "float32"}, sys.stdout)
+`
+
+	script := filepath.Join(t.TempDir(), "env.py")
+
+	err = os.WriteFile(script, []byte(script_body), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write script, %v", err)
+	}
+
+	t.Setenv("EXEC_TEST", "secret")
+	os.Unsetenv("EXEC_TEST_UNSET")
+
+	uri := fmt.Sprintf("exec://%s?arg=%s&env=EXEC_TEST_UNSET", python, script)
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if rsp.Embeddings()[0] != 0 {
+		t.Fatalf("Expected an empty environment, got '%s'", rsp.Model())
+	}
+}
@@ -0,0 +1,282 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverEmbedder implements the `Embedder` interface by sending requests to an ordered list of equivalent
+// `Embedder` instances, moving on to the next one when a request fails with a retryable error.
+type FailoverEmbedder[T Float] struct {
+	Embedder[T]
+	backends []*failoverBackend[T]
+	cooldown time.Duration
+}
+
+// failoverBackend tracks the health of an individual backend.
+type failoverBackend[T Float] struct {
+	label           string
+	model           string
+	embedder        Embedder[T]
+	mu              sync.Mutex
+	unhealthy_until time.Time
+	failures        int
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "failover", NewFailoverEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "failover32", NewFailoverEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "failover64", NewFailoverEmbedder[float64])
+}
+
+// NewFailoverEmbedder creates a new `FailoverEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	failover://?client-uri={CLIENT_URI}&client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of an underlying `Embedder`, in order of preference. May be repeated. Each URI may be followed by "..." and
+// the name of the model it serves; otherwise the value of its own `?model=` parameter, if present, is used. It is an error for backends
+// to declare different models.
+// * `cooldown` – The number of seconds a backend is skipped after it fails with a retryable error. Default is 30.
+func NewFailoverEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	client_uris := q["client-uri"]
+
+	if len(client_uris) == 0 {
+		return nil, fmt.Errorf("A minimum of (1) ?client-uri= parameters is required")
+	}
+
+	cooldown := 30 * time.Second
+
+	if q.Has("cooldown") {
+
+		v, err := strconv.Atoi(q.Get("cooldown"))
+
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("Invalid ?cooldown= parameter")
+		}
+
+		cooldown = time.Duration(v) * time.Second
+	}
+
+	backends := make([]*failoverBackend[T], 0)
+	backend_uris := make([]string, 0)
+	declared_model := ""
+
+	// Parse and check every backend before any of them are created so that none are left running if one is invalid
+
+	for idx, str_spec := range client_uris {
+
+		client_uri, model, err := parseModelSpec(str_spec)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if model != "" {
+
+			if declared_model != "" && model != declared_model {
+				return nil, fmt.Errorf("Refusing to fail over between backends with different models: '%s' and '%s'", declared_model, model)
+			}
+
+			declared_model = model
+		}
+
+		b := &failoverBackend[T]{
+			label: fmt.Sprintf("%d:%s", idx, embedderLabel(client_uri)),
+			model: model,
+		}
+
+		backends = append(backends, b)
+		backend_uris = append(backend_uris, client_uri)
+	}
+
+	for idx, b := range backends {
+
+		client_uri := backend_uris[idx]
+
+		cl, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+		if err != nil {
+
+			// Close the backends which have already been created so that they are not left running
+
+			closeEmbedders(ctx, failoverEmbedders(backends[:idx]))
+			return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+		}
+
+		b.embedder = cl
+	}
+
+	e := &FailoverEmbedder[T]{
+		backends: backends,
+		cooldown: cooldown,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives text embeddings using the first healthy backend that does not fail with a retryable error.
+func (e *FailoverEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, func(cl Embedder[T]) (EmbeddingsResponse[T], error) {
+		return cl.TextEmbeddings(ctx, req)
+	})
+}
+
+// ImageEmbeddings derives image embeddings using the first healthy backend that does not fail with a retryable error.
+func (e *FailoverEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, func(cl Embedder[T]) (EmbeddingsResponse[T], error) {
+		return cl.ImageEmbeddings(ctx, req)
+	})
+}
+
+// Close closes each of the underlying embedders, returning any errors joined together.
+func (e *FailoverEmbedder[T]) Close(ctx context.Context) error {
+	return closeEmbedders(ctx, failoverEmbedders(e.backends))
+}
+
+func failoverEmbedders[T Float](backends []*failoverBackend[T]) []Embedder[T] {
+
+	embedders := make([]Embedder[T], len(backends))
+
+	for idx, b := range backends {
+		embedders[idx] = b.embedder
+	}
+
+	return embedders
+}
+
+func (e *FailoverEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, fn func(Embedder[T]) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	// Backends which are cooling down are only tried, as a last resort, once all the healthy backends have failed.
+
+	now := time.Now()
+
+	healthy := make([]*failoverBackend[T], 0)
+	cooling := make([]*failoverBackend[T], 0)
+
+	for _, b := range e.backends {
+
+		if req.Model != "" && b.model != "" && req.Model != b.model {
+			return nil, fmt.Errorf("Requested model '%s' does not match backend model '%s'", req.Model, b.model)
+		}
+
+		if b.isHealthy(now) {
+			healthy = append(healthy, b)
+		} else {
+			cooling = append(cooling, b)
+		}
+	}
+
+	var last_err error
+
+	for _, b := range append(healthy, cooling...) {
+
+		rsp, err := fn(b.embedder)
+
+		if err == nil {
+			b.markHealthy()
+			return rsp, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if !IsRetryableError(err) {
+			return nil, err
+		}
+
+		b.markUnhealthy(e.cooldown)
+		slog.Warn("Backend failed, failing over", "backend", b.label, "error", err)
+
+		last_err = err
+	}
+
+	return nil, fmt.Errorf("All backends failed, last error: %w", last_err)
+}
+
+func (b *failoverBackend[T]) isHealthy(now time.Time) bool {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !now.Before(b.unhealthy_until)
+}
+
+func (b *failoverBackend[T]) markHealthy() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures > 0 {
+		slog.Info("Backend recovered", "backend", b.label)
+	}
+
+	b.failures = 0
+	b.unhealthy_until = time.Time{}
+}
+
+func (b *failoverBackend[T]) markUnhealthy(cooldown time.Duration) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures += 1
+	b.unhealthy_until = time.Now().Add(cooldown)
+}
+
+// parseModelSpec parses a `?client-uri=` parameter in the form of "{CLIENT_URI}" or "{CLIENT_URI}...{MODEL}" returning the
+// client URI and the model it declares. If no model is appended to the URI the value of its own `?model=` parameter is used.
+func parseModelSpec(spec string) (string, string, error) {
+
+	client_uri, model, found := strings.Cut(spec, ROUTE_SEPARATOR)
+
+	if found {
+
+		if model == "" || strings.Contains(model, ROUTE_SEPARATOR) {
+			return "", "", fmt.Errorf("?client-uri= parameter must be in the form of '{CLIENT_URI}' or '{CLIENT_URI}%s{MODEL}'", ROUTE_SEPARATOR)
+		}
+
+		return client_uri, model, nil
+	}
+
+	u, err := url.Parse(client_uri)
+
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to parse client URI, %w", err)
+	}
+
+	return client_uri, u.Query().Get("model"), nil
+}
+
+// embedderLabel returns a label for 'uri', suitable for logging, which omits any query parameters (and
+// user information) since they may contain credentials.
+func embedderLabel(uri string) string {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return "invalid-uri"
+	}
+
+	label := u.Scheme + "://" + u.Host + u.Path
+	return label
+}
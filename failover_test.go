@@ -0,0 +1,148 @@
+package embeddings
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFailoverEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	uri := "failover://?client-uri=testing://%3Fmodel=primary%26error=retryable%26failures=1...testing&client-uri=testing://%3Fmodel=secondary...testing&cooldown=60"
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	// The primary fails (once) with a retryable error so the secondary is used
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if rsp.Model() != "secondary" {
+		t.Fatalf("Expected secondary backend, got %s", rsp.Model())
+	}
+
+	// The primary is now cooling down so the secondary is used even though the primary would succeed
+
+	rsp, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if rsp.Model() != "secondary" {
+		t.Fatalf("Expected secondary backend while primary is cooling down, got %s", rsp.Model())
+	}
+}
+
+func TestFailoverEmbedderFatal(t *testing.T) {
+
+	ctx := context.Background()
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	uri := "failover://?client-uri=testing://%3Ferror=fatal&client-uri=testing://"
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected non-retryable error to be returned without failing over")
+	}
+}
+
+func TestFailoverEmbedderAllFailed(t *testing.T) {
+
+	ctx := context.Background()
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	uri := "failover://?client-uri=testing://%3Ferror=retryable&client-uri=testing://%3Ferror=retryable"
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected request to fail when all backends fail")
+	}
+
+	if !IsRetryableError(err) {
+		t.Fatalf("Expected wrapped error to be retryable, %v", err)
+	}
+}
+
+func TestFailoverEmbedderModels(t *testing.T) {
+
+	ctx := context.Background()
+
+	uri := "failover://?client-uri=testing://%3Fmodel=embeddinggemma&client-uri=testing://...nomic-embed-text"
+
+	_, err := NewEmbedder32(ctx, uri)
+
+	if err == nil {
+		t.Fatalf("Expected backends with different models to fail")
+	}
+}
+
+func TestFailoverEmbedderClose(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	// Backends which have already been created are closed if a later backend can not be created
+
+	_, err := NewEmbedder32(ctx, "failover://?client-uri=testing://&client-uri=unknown://")
+
+	if err == nil {
+		t.Fatalf("Expected unknown backend to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected backends not to be left open")
+	}
+
+	// Every backend is closed even if closing an earlier one fails
+
+	emb, err := NewEmbedder32(ctx, "failover://?client-uri=testing://%3Fclose-error=true&client-uri=testing://%3Fclose-error=true")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	err = CloseEmbedder(ctx, emb)
+
+	if err == nil || strings.Count(err.Error(), "Testing close error") != 2 {
+		t.Fatalf("Expected both close errors, got %v", err)
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected all backends to be closed")
+	}
+}
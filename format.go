@@ -0,0 +1,121 @@
+package embeddings
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"unsafe"
+)
+
+const (
+	// JSON-encoded responses.
+	FORMAT_JSON string = "json"
+	// Embeddings as little-endian float32 or float64 values, with no header.
+	FORMAT_RAW string = "raw"
+	// Embeddings as a NumPy .npy file.
+	FORMAT_NPY string = "npy"
+	// Responses as a NumPy .npz archive containing "embeddings", "id", "model" and "precision" arrays.
+	FORMAT_NPZ string = "npz"
+	// Responses as length-prefixed binary records. See `RecordEncoder` for details.
+	FORMAT_RECORD string = "record"
+)
+
+// IsValidFormat returns a boolean value indicating whether 'format' is a known serialization format.
+func IsValidFormat(format string) bool {
+
+	switch format {
+	case FORMAT_JSON, FORMAT_RAW, FORMAT_NPY, FORMAT_NPZ, FORMAT_RECORD:
+		return true
+	default:
+		return false
+	}
+}
+
+// EncodeResponse writes 'rsp' to 'wr' using 'format'.
+func EncodeResponse[T Float](wr io.Writer, rsp EmbeddingsResponse[T], format string) error {
+
+	switch format {
+	case FORMAT_JSON:
+		return json.NewEncoder(wr).Encode(rsp)
+	case FORMAT_RAW:
+		return EncodeRaw(wr, rsp.Embeddings())
+	case FORMAT_NPY:
+		return WriteNPY(wr, rsp.Embeddings())
+	case FORMAT_NPZ:
+		return EncodeNPZ(wr, rsp)
+	case FORMAT_RECORD:
+		return NewRecordEncoder[T](wr).Encode(rsp)
+	default:
+		return fmt.Errorf("Invalid or unsupported format '%s'", format)
+	}
+}
+
+// EncodeRaw writes 'vector' to 'wr' as little-endian float32 or float64 values (depending on T).
+func EncodeRaw[T Float](wr io.Writer, vector []T) error {
+
+	_, err := wr.Write(floatBytes(vector))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write embeddings, %w", err)
+	}
+
+	return nil
+}
+
+// DecodeRaw decodes 'body' as little-endian float32 or float64 values (depending on T).
+func DecodeRaw[T Float](body []byte) ([]T, error) {
+	return floatsFromBytes[T](body, floatWidth[T]())
+}
+
+// floatWidth returns the size, in bytes, of T.
+func floatWidth[T Float]() int {
+	var v T
+	return int(unsafe.Sizeof(v))
+}
+
+// floatBytes returns 'vector' encoded as little-endian float32 or float64 values (depending on T).
+func floatBytes[T Float](vector []T) []byte {
+
+	width := floatWidth[T]()
+	buf := make([]byte, len(vector)*width)
+
+	for i, f := range vector {
+
+		switch width {
+		case 4:
+			binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(f)))
+		default:
+			binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(float64(f)))
+		}
+	}
+
+	return buf
+}
+
+// floatsFromBytes decodes 'body' as little-endian floating point values of 'width' (4 or 8) bytes, converting them to T.
+func floatsFromBytes[T Float](body []byte, width int) ([]T, error) {
+
+	if width != 4 && width != 8 {
+		return nil, fmt.Errorf("Invalid or unsupported float width %d", width)
+	}
+
+	if len(body)%width != 0 {
+		return nil, fmt.Errorf("Data length (%d) is not a multiple of %d", len(body), width)
+	}
+
+	vector := make([]T, len(body)/width)
+
+	for i := range vector {
+
+		switch width {
+		case 4:
+			vector[i] = T(math.Float32frombits(binary.LittleEndian.Uint32(body[i*4:])))
+		default:
+			vector[i] = T(math.Float64frombits(binary.LittleEndian.Uint64(body[i*8:])))
+		}
+	}
+
+	return vector, nil
+}
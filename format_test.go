@@ -0,0 +1,257 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+func testingResponse() *CommonEmbeddingsResponse[float32] {
+
+	rsp := &CommonEmbeddingsResponse[float32]{
+		CommonId:         "1527845303",
+		CommonModel:      "testing/walrus",
+		CommonCreated:    1767225600,
+		CommonPrecision:  "float32",
+		CommonEmbeddings: []float32{0.5, -1.25, 3.0, 0.0},
+	}
+
+	return rsp
+}
+
+func TestRawFormat(t *testing.T) {
+
+	rsp := testingResponse()
+
+	var buf bytes.Buffer
+
+	err := EncodeResponse[float32](&buf, rsp, FORMAT_RAW)
+
+	if err != nil {
+		t.Fatalf("Failed to encode response, %v", err)
+	}
+
+	if buf.Len() != 16 {
+		t.Fatalf("Unexpected length: %d", buf.Len())
+	}
+
+	v, err := DecodeRaw[float32](buf.Bytes())
+
+	if err != nil {
+		t.Fatalf("Failed to decode raw data, %v", err)
+	}
+
+	for i := range v {
+
+		if v[i] != rsp.CommonEmbeddings[i] {
+			t.Fatalf("Unexpected value at %d: %f", i, v[i])
+		}
+	}
+}
+
+func TestNPYFormat(t *testing.T) {
+
+	vectors := [][]float64{
+		{1, 2, 3},
+		{4, 5, 6},
+	}
+
+	var buf bytes.Buffer
+
+	err := WriteNPYMatrix(&buf, vectors)
+
+	if err != nil {
+		t.Fatalf("Failed to write matrix, %v", err)
+	}
+
+	// The header must be padded so that data starts on a 64-byte boundary
+
+	if (buf.Len()-(6*8))%64 != 0 {
+		t.Fatalf("Unexpected header length: %d", buf.Len()-(6*8))
+	}
+
+	v, shape, err := ReadNPY[float32](&buf)
+
+	if err != nil {
+		t.Fatalf("Failed to read matrix, %v", err)
+	}
+
+	if len(shape) != 2 || shape[0] != 2 || shape[1] != 3 {
+		t.Fatalf("Unexpected shape: %v", shape)
+	}
+
+	if len(v) != 6 || v[5] != 6 {
+		t.Fatalf("Unexpected values: %v", v)
+	}
+}
+
+func TestNPYFormatCorrupt(t *testing.T) {
+
+	header := func(descr string, shape []int) []byte {
+
+		var buf bytes.Buffer
+
+		err := writeNPYHeader(&buf, descr, shape)
+
+		if err != nil {
+			t.Fatalf("Failed to write header, %v", err)
+		}
+
+		return buf.Bytes()
+	}
+
+	// Negative dimensions whose product matches the length of the data and a shape whose size overflows
+
+	negative := append(header("<f4", []int{-1, -4}), make([]byte, 16)...)
+	overflow := header("<f8", []int{math.MaxInt / 2, 4})
+
+	// A version 2 header whose length exceeds the maximum
+
+	huge_header := []byte(npy_magic + "\x02\x00")
+	huge_header = binary.LittleEndian.AppendUint32(huge_header, math.MaxUint32)
+
+	tests := map[string][]byte{
+		"negative dimensions": negative,
+		"overflowing shape":   overflow,
+		"huge header":         huge_header,
+	}
+
+	for name, body := range tests {
+
+		_, _, err := ReadNPY[float32](bytes.NewReader(body))
+
+		if err == nil {
+			t.Fatalf("Expected %s to fail", name)
+		}
+	}
+
+	for _, descr := range []string{"<U-5", "<U0", "<U99999999999"} {
+
+		_, err := readNPYString(bytes.NewReader(header(descr, []int{})))
+
+		if err == nil {
+			t.Fatalf("Expected string with data type '%s' to fail", descr)
+		}
+	}
+}
+
+func TestNPZFormat(t *testing.T) {
+
+	rsp := testingResponse()
+
+	var buf bytes.Buffer
+
+	err := EncodeResponse[float32](&buf, rsp, FORMAT_NPZ)
+
+	if err != nil {
+		t.Fatalf("Failed to encode response, %v", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+
+	dec_rsp, err := DecodeNPZ[float64](r, r.Size())
+
+	if err != nil {
+		t.Fatalf("Failed to decode response, %v", err)
+	}
+
+	if dec_rsp.Id() != rsp.Id() || dec_rsp.Model() != rsp.Model() || dec_rsp.Precision() != rsp.Precision() {
+		t.Fatalf("Unexpected response: %v", dec_rsp)
+	}
+
+	if dec_rsp.Dimensions() != 4 || dec_rsp.Embeddings()[1] != -1.25 {
+		t.Fatalf("Unexpected embeddings: %v", dec_rsp.Embeddings())
+	}
+}
+
+func TestRecordFormat(t *testing.T) {
+
+	rsp := testingResponse()
+
+	var buf bytes.Buffer
+
+	enc := NewRecordEncoder[float32](&buf)
+
+	for i := 0; i < 2; i++ {
+
+		err := enc.Encode(rsp)
+
+		if err != nil {
+			t.Fatalf("Failed to encode record, %v", err)
+		}
+	}
+
+	dec := NewRecordDecoder[float64](&buf)
+	count := 0
+
+	for {
+
+		dec_rsp, err := dec.Decode()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to decode record, %v", err)
+		}
+
+		if dec_rsp.Id() != rsp.Id() || dec_rsp.Model() != rsp.Model() || dec_rsp.Precision() != rsp.Precision() || dec_rsp.Created() != rsp.Created() {
+			t.Fatalf("Unexpected record: %v", dec_rsp)
+		}
+
+		if dec_rsp.Dimensions() != 4 || dec_rsp.Embeddings()[2] != 3.0 {
+			t.Fatalf("Unexpected embeddings: %v", dec_rsp.Embeddings())
+		}
+
+		count += 1
+	}
+
+	if count != 2 {
+		t.Fatalf("Unexpected number of records: %d", count)
+	}
+}
+
+func TestRecordFormatCorrupt(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	enc := NewRecordEncoder[float32](&buf)
+
+	err := enc.Encode(testingResponse())
+
+	if err != nil {
+		t.Fatalf("Failed to encode record, %v", err)
+	}
+
+	record := buf.Bytes()
+
+	// Length exceeding the maximum, truncated record and dimensions exceeding the record length
+
+	huge_length := bytes.Clone(record)
+	binary.LittleEndian.PutUint32(huge_length[4:], math.MaxUint32)
+
+	truncated := bytes.Clone(record[:len(record)-3])
+
+	huge_dimensions := bytes.Clone(record)
+	binary.LittleEndian.PutUint32(huge_dimensions[len(record)-20:], math.MaxUint32)
+
+	tests := map[string][]byte{
+		"huge length":     huge_length,
+		"truncated":       truncated,
+		"huge dimensions": huge_dimensions,
+	}
+
+	for name, body := range tests {
+
+		dec := NewRecordDecoder[float32](bytes.NewReader(body))
+
+		_, err := dec.Decode()
+
+		if err == nil || err == io.EOF {
+			t.Fatalf("Expected %s record to fail, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package embeddings
+
+// Pure-Go image handling used by the preprocess:// and tile:// embedders.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// Scale images so that they fit within the target dimensions, preserving their aspect ratio.
+	RESIZE_FIT string = "fit"
+	// Scale images so that they cover the target dimensions, preserving their aspect ratio, and then crop the center of the image.
+	RESIZE_CROP string = "crop"
+)
+
+// The default maximum number of pixels (width x height) in images decoded by the preprocess:// and tile:// embedders.
+// Decoded images are held in memory as 8-bit RGBA so this limits each image to roughly 256MB.
+const DEFAULT_IMAGE_MAX_PIXELS int = 64 << 20
+
+const exif_orientation_tag uint16 = 0x0112
+
+// DecodeImage decodes 'body' (JPEG, PNG, GIF, WebP or TIFF) applying any EXIF orientation and returning an
+// 8-bit RGB image. Transparent pixels are composited on to a white background. The name of the source image format is also returned.
+// Images with more than 'max_pixels' pixels are rejected before they are decoded; a value of 0 means there is no limit.
+func DecodeImage(body []byte, max_pixels int) (*image.NRGBA, string, error) {
+
+	if max_pixels > 0 {
+
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to decode image configuration, %w", err)
+		}
+
+		pixels := int64(cfg.Width) * int64(cfg.Height)
+
+		if pixels > int64(max_pixels) {
+			return nil, "", fmt.Errorf("Image dimensions (%d x %d) exceed the maximum of %d pixels", cfg.Width, cfg.Height, max_pixels)
+		}
+	}
+
+	im, format, err := image.Decode(bytes.NewReader(body))
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to decode image, %w", err)
+	}
+
+	rgb := toRGB(im)
+
+	orientation := imageOrientation(body)
+
+	if orientation > 1 {
+		rgb = orient(rgb, orientation)
+	}
+
+	return rgb, format, nil
+}
+
+// ResizeImage scales 'im' to 'width' x 'height' using 'mode' ("fit" or "crop"). If 'upscale' is false
+// images smaller than the target dimensions are not enlarged.
+func ResizeImage(im image.Image, width int, height int, mode string, upscale bool) (*image.NRGBA, error) {
+
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("Invalid dimensions")
+	}
+
+	b := im.Bounds()
+	src_w := float64(b.Dx())
+	src_h := float64(b.Dy())
+
+	var scale float64
+
+	switch mode {
+	case RESIZE_FIT:
+		scale = min(float64(width)/src_w, float64(height)/src_h)
+	case RESIZE_CROP:
+		scale = max(float64(width)/src_w, float64(height)/src_h)
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported resize mode '%s'", mode)
+	}
+
+	if scale > 1.0 && !upscale {
+		scale = 1.0
+	}
+
+	scaled_w := max(1, int(src_w*scale+0.5))
+	scaled_h := max(1, int(src_h*scale+0.5))
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, scaled_w, scaled_h))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), im, b, draw.Src, nil)
+
+	if mode == RESIZE_FIT {
+		return scaled, nil
+	}
+
+	crop_w := min(width, scaled_w)
+	crop_h := min(height, scaled_h)
+
+	x := (scaled_w - crop_w) / 2
+	y := (scaled_h - crop_h) / 2
+
+	return CropImage(scaled, image.Rect(x, y, x+crop_w, y+crop_h)), nil
+}
+
+// CropImage returns a copy of the region 'r' of 'im'.
+func CropImage(im image.Image, r image.Rectangle) *image.NRGBA {
+
+	r = r.Intersect(im.Bounds())
+
+	cropped := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), im, r.Min, draw.Src)
+
+	return cropped
+}
+
+// EncodeImage encodes 'im' using 'format' ("jpeg" or "png"). 'quality' is only used for JPEG images.
+func EncodeImage(im image.Image, format string, quality int) ([]byte, error) {
+
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case "jpeg", "jpg":
+
+		opts := &jpeg.Options{
+			Quality: quality,
+		}
+
+		err = jpeg.Encode(&buf, im, opts)
+
+	case "png":
+		err = png.Encode(&buf, im)
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported image format '%s'", format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode image, %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// toRGB converts 'im' (which may be CMYK, YCbCr, grayscale, paletted, etc.) to an opaque NRGBA image
+// compositing any transparent pixels on to a white background.
+func toRGB(im image.Image) *image.NRGBA {
+
+	b := im.Bounds()
+	rgb := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	draw.Draw(rgb, rgb.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgb, rgb.Bounds(), im, b.Min, draw.Over)
+
+	return rgb
+}
+
+// orient applies the EXIF 'orientation' transformation (2-8) to 'im'.
+func orient(im *image.NRGBA, orientation int) *image.NRGBA {
+
+	w := im.Bounds().Dx()
+	h := im.Bounds().Dy()
+
+	dst_w, dst_h := w, h
+
+	if orientation >= 5 {
+		dst_w, dst_h = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dst_w, dst_h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+
+			var dx, dy int
+
+			switch orientation {
+			case 2: // flip horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counter-clockwise
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+
+			dst.SetNRGBA(dx, dy, im.NRGBAAt(x, y))
+		}
+	}
+
+	return dst
+}
+
+// imageOrientation returns the EXIF orientation value for 'body' (a JPEG, TIFF or WebP image) or 0 if it can not be determined.
+func imageOrientation(body []byte) int {
+
+	switch {
+	case len(body) > 2 && body[0] == 0xFF && body[1] == 0xD8:
+		return jpegOrientation(body)
+	case len(body) > 12 && string(body[0:4]) == "RIFF" && string(body[8:12]) == "WEBP":
+		return webpOrientation(body)
+	case len(body) > 4 && (string(body[0:4]) == "II*\x00" || string(body[0:4]) == "MM\x00*"):
+		return tiffOrientation(body)
+	default:
+		return 0
+	}
+}
+
+func jpegOrientation(body []byte) int {
+
+	offset := 2
+
+	for offset+4 <= len(body) {
+
+		if body[offset] != 0xFF {
+			return 0
+		}
+
+		marker := body[offset+1]
+
+		// Start of scan; there is no more metadata
+
+		if marker == 0xDA {
+			return 0
+		}
+
+		length := int(binary.BigEndian.Uint16(body[offset+2 : offset+4]))
+		start := offset + 4
+		end := offset + 2 + length
+
+		if length < 2 || end > len(body) {
+			return 0
+		}
+
+		if marker == 0xE1 && end-start > 6 && string(body[start:start+6]) == "Exif\x00\x00" {
+			return tiffOrientation(body[start+6 : end])
+		}
+
+		offset = end
+	}
+
+	return 0
+}
+
+func webpOrientation(body []byte) int {
+
+	offset := 12
+
+	for offset+8 <= len(body) {
+
+		id := string(body[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(body[offset+4 : offset+8]))
+		start := offset + 8
+		end := start + size
+
+		if size < 0 || end > len(body) {
+			return 0
+		}
+
+		if id == "EXIF" {
+
+			data := body[start:end]
+
+			if len(data) > 6 && string(data[0:6]) == "Exif\x00\x00" {
+				data = data[6:]
+			}
+
+			return tiffOrientation(data)
+		}
+
+		// Chunks are padded to an even number of bytes
+		offset = end + (size % 2)
+	}
+
+	return 0
+}
+
+// tiffOrientation returns the orientation tag from the first IFD of the TIFF-encoded data in 'body'.
+func tiffOrientation(body []byte) int {
+
+	if len(body) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+
+	switch string(body[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(body[4:8]))
+
+	if ifd+2 > len(body) {
+		return 0
+	}
+
+	count := int(order.Uint16(body[ifd : ifd+2]))
+
+	for i := 0; i < count; i++ {
+
+		entry := ifd + 2 + (i * 12)
+
+		if entry+12 > len(body) {
+			return 0
+		}
+
+		tag := order.Uint16(body[entry : entry+2])
+
+		if tag != exif_orientation_tag {
+			continue
+		}
+
+		v := int(order.Uint16(body[entry+8 : entry+10]))
+
+		if v < 1 || v > 8 {
+			return 0
+		}
+
+		return v
+	}
+
+	return 0
+}
@@ -0,0 +1,22 @@
+// Package latency provides helpers for summarizing the time taken by embeddings requests, shared by the
+// eval package and the embeddings application.
+package latency
+
+import (
+	"math"
+	"time"
+)
+
+// Percentile returns the nearest-rank percentile 'p' (0-1) of 'sorted', which must be sorted and non-empty.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	idx = max(0, min(idx, len(sorted)-1))
+
+	return sorted[idx]
+}
+
+// Milliseconds returns 'd' as a (fractional) number of milliseconds.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,38 @@
+package latency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+
+	sorted := make([]time.Duration, 100)
+
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := map[float64]float64{
+		0:    1,
+		0.50: 50,
+		0.95: 95,
+		0.99: 99,
+		1:    100,
+	}
+
+	for p, expected := range tests {
+
+		v := Milliseconds(Percentile(sorted, p))
+
+		if v != expected {
+			t.Fatalf("Unexpected value for p%.0f, expected %f but got %f", p*100, expected, v)
+		}
+	}
+
+	v := Percentile([]time.Duration{time.Second}, 0.99)
+
+	if v != time.Second {
+		t.Fatalf("Unexpected value for single duration, %v", v)
+	}
+}
@@ -21,6 +21,9 @@ type LlamafileEmbedder[T Float] struct {
 	Embedder[T]
 	client    *llamafileClient
 	precision string
+	tasks     *taskOptions
+	// The model declared by the `?model=` parameter, used to match task templates when a request does not specify one.
+	model string
 }
 
 func init() {
@@ -52,6 +55,12 @@ func NewLlamafileEmbedder[T Float](ctx context.Context, uri string) (Embedder[T]
 		return nil, err
 	}
 
+	tasks, err := taskOptionsFromQuery(q)
+
+	if err != nil {
+		return nil, err
+	}
+
 	precision := "float64"
 
 	if strings.HasSuffix(u.Scheme, "32") {
@@ -61,6 +70,8 @@ func NewLlamafileEmbedder[T Float](ctx context.Context, uri string) (Embedder[T]
 	e := &LlamafileEmbedder[T]{
 		client:    llamafile_cl,
 		precision: precision,
+		tasks:     tasks,
+		model:     q.Get("model"),
 	}
 
 	return e, nil
@@ -68,8 +79,20 @@ func NewLlamafileEmbedder[T Float](ctx context.Context, uri string) (Embedder[T]
 
 func (e *LlamafileEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
+	model := req.Model
+
+	if model == "" {
+		model = e.model
+	}
+
+	body, err := e.tasks.apply(req, model)
+
+	if err != nil {
+		return nil, err
+	}
+
 	ll_req := &llamafileEmbeddingRequest{
-		Content: string(req.Body),
+		Content: string(body),
 	}
 
 	ll_rsp, err := e.client.embeddings(ctx, ll_req)
@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type llamafileImageDataEmbeddingRequest struct {
@@ -113,26 +115,34 @@ func (e *llamafileClient) embeddings(ctx context.Context, llamafile_req *llamafi
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	injectTraceHeaders(ctx, req.Header)
+
+	t1 := time.Now()
 
 	rsp, err := e.client.Do(req)
 
 	if err != nil {
+		logHTTPRequest(ctx, req, 0, t1, len(enc_msg), -1, "", err)
 		return nil, fmt.Errorf("Failed to execute request, %w", err)
 	}
 
 	defer rsp.Body.Close()
 
-	if rsp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Embeddings request failed %d: %s", rsp.StatusCode, rsp.Status)
+	body, err := io.ReadAll(rsp.Body)
+
+	logHTTPRequest(ctx, req, rsp.StatusCode, t1, len(enc_msg), len(body), "", err)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response, %w", err)
 	}
 
-	// body, _ := io.ReadAll(rsp.Body)
-	// fmt.Println("WUT", string(body))
+	if rsp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: rsp.StatusCode, Status: rsp.Status}
+	}
 
 	var llamafile_rsp *llamafileEmbeddingResponse
 
-	dec := json.NewDecoder(rsp.Body)
-	err = dec.Decode(&llamafile_rsp)
+	err = json.Unmarshal(body, &llamafile_rsp)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to unmarshal embeddings, %w", err)
@@ -8,11 +8,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	_ "log/slog"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type LocalClientImageDataEmbeddingRequest struct {
@@ -95,6 +96,59 @@ func NewLocalClient(ctx context.Context, uri string) (*LocalClient, error) {
 	return cl, nil
 }
 
+// newLocalClientFromQuery returns a new `LocalClient` instance for the server defined by the `?server-uri=`
+// parameter in 'q'. If 'q' contains a `?launch=` parameter then a new `LocalServer` instance will be started
+// (see `NewLocalServerFromQuery` for details) and returned alongside a `LocalClient` instance configured to
+// talk to it. If there is no `?script=` parameter the bundled script named 'default_script' will be launched.
+// It is the caller's responsibility to close that server.
+func newLocalClientFromQuery(ctx context.Context, q url.Values, default_script string) (*LocalClient, *LocalServer, error) {
+
+	client_uri := "http://localhost:5000"
+
+	if q.Has("server-uri") {
+		client_uri = q.Get("server-uri")
+	}
+
+	var server *LocalServer
+
+	if q.Has("launch") {
+
+		if q.Has("server-uri") {
+			return nil, nil, fmt.Errorf("?server-uri= and ?launch= parameters are mutually exclusive")
+		}
+
+		script, err := ensureScript(q.Get("script"), default_script)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		q.Set("script", script)
+
+		s, err := NewLocalServerFromQuery(ctx, q)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to launch local server, %w", err)
+		}
+
+		server = s
+		client_uri = server.URI()
+	}
+
+	cl, err := NewLocalClient(ctx, client_uri)
+
+	if err != nil {
+
+		if server != nil {
+			server.Close(ctx)
+		}
+
+		return nil, nil, err
+	}
+
+	return cl, server, nil
+}
+
 func (e *LocalClient) embeddings(ctx context.Context, local_req *LocalClientEmbeddingRequest) (*LocalClientEmbeddingResponse, error) {
 
 	u := url.URL{}
@@ -118,8 +172,6 @@ func (e *LocalClient) embeddings(ctx context.Context, local_req *LocalClientEmbe
 
 	endpoint := u.String()
 
-	// slog.Debug("Calling endpoint", "uri", endpoint)
-
 	enc_msg, err := json.Marshal(local_req)
 
 	if err != nil {
@@ -135,23 +187,34 @@ func (e *LocalClient) embeddings(ctx context.Context, local_req *LocalClientEmbe
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	injectTraceHeaders(ctx, req.Header)
+
+	t1 := time.Now()
 
 	rsp, err := e.client.Do(req)
 
 	if err != nil {
+		logHTTPRequest(ctx, req, 0, t1, len(enc_msg), -1, "", err)
 		return nil, fmt.Errorf("Failed to execute request, %w", err)
 	}
 
 	defer rsp.Body.Close()
 
+	body, err := io.ReadAll(rsp.Body)
+
+	logHTTPRequest(ctx, req, rsp.StatusCode, t1, len(enc_msg), len(body), "", err)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response, %w", err)
+	}
+
 	if rsp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Embeddings request failed %d: %s", rsp.StatusCode, rsp.Status)
+		return nil, &HTTPError{StatusCode: rsp.StatusCode, Status: rsp.Status}
 	}
 
 	var local_rsp *LocalClientEmbeddingResponse
 
-	dec := json.NewDecoder(rsp.Body)
-	err = dec.Decode(&local_rsp)
+	err = json.Unmarshal(body, &local_rsp)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to unmarshal embeddings, %w", err)
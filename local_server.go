@@ -0,0 +1,383 @@
+package embeddings
+
+// For managing the lifecycle of the simple HTTP "server" wrappers for non-Go embeddings interfaces
+// (for example siglip_server_py.txt) as subprocesses of the current application.
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// The default amount of time to wait for a local server to start accepting connections. This is
+// deliberately generous because most servers need to load a model in to memory before they start.
+const LOCAL_SERVER_READY_TIMEOUT time.Duration = 5 * time.Minute
+
+// The default amount of time to wait for a local server to exit after it has been asked to stop
+// before it is killed.
+const LOCAL_SERVER_STOP_TIMEOUT time.Duration = 10 * time.Second
+
+// The maximum amount of time to wait between attempts to restart a local server that has crashed.
+const LOCAL_SERVER_MAX_BACKOFF time.Duration = 30 * time.Second
+
+// LocalServer manages a local HTTP server (typically one of the FastAPI scripts bundled with this
+// package) running as a subprocess. The server is started on a free port, its output is relayed
+// to the default `slog` logger and it is restarted if it exits unexpectedly until `Close` is called.
+type LocalServer struct {
+	python        string
+	script        string
+	args          []string
+	host          string
+	port          int
+	ready_timeout time.Duration
+	restart       bool
+	mu            *sync.Mutex
+	cmd           *exec.Cmd
+	exited        chan struct{}
+	closed        bool
+}
+
+// NewLocalServerFromQuery creates and starts a new `LocalServer` instance derived from the following query parameters:
+// * `launch` – The path to the (Python) runtime used to start the server.
+// * `script` – The path to the server script to run.
+// * `script-arg` – Zero or more additional arguments to pass to the server script, for example "--model_name=google/siglip-base-patch16-224".
+// * `launch-timeout` – The maximum number of seconds to wait for the server to start accepting connections. Default is 300.
+// * `restart` – A boolean flag indicating whether the server should be restarted if it exits unexpectedly. Default is true.
+//
+// The server script is expected to accept `--host` and `--port` arguments which will be appended to the
+// list of arguments passed to it.
+func NewLocalServerFromQuery(ctx context.Context, q url.Values) (*LocalServer, error) {
+
+	if !q.Has("launch") {
+		return nil, fmt.Errorf("Missing ?launch= parameter")
+	}
+
+	if !q.Has("script") {
+		return nil, fmt.Errorf("Missing ?script= parameter")
+	}
+
+	python := q.Get("launch")
+
+	script, err := filepath.Abs(q.Get("script"))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive absolute path for script, %w", err)
+	}
+
+	ready_timeout := LOCAL_SERVER_READY_TIMEOUT
+
+	if q.Has("launch-timeout") {
+
+		v, err := strconv.Atoi(q.Get("launch-timeout"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?launch-timeout= parameter, %w", err)
+		}
+
+		ready_timeout = time.Duration(v) * time.Second
+	}
+
+	restart := true
+
+	if q.Has("restart") {
+
+		v, err := strconv.ParseBool(q.Get("restart"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?restart= parameter, %w", err)
+		}
+
+		restart = v
+	}
+
+	port, err := freePort()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to determine free port, %w", err)
+	}
+
+	s := &LocalServer{
+		python:        python,
+		script:        script,
+		args:          q["script-arg"],
+		host:          "127.0.0.1",
+		port:          port,
+		ready_timeout: ready_timeout,
+		restart:       restart,
+		mu:            new(sync.Mutex),
+	}
+
+	err = s.start(ctx)
+
+	if err != nil {
+		s.closed = true
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// URI returns the HTTP URI of the server being managed.
+func (s *LocalServer) URI() string {
+	return fmt.Sprintf("http://%s", s.address())
+}
+
+// Close stops the server and prevents it from being restarted.
+func (s *LocalServer) Close(ctx context.Context) error {
+
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.closed = true
+
+	cmd := s.cmd
+	exited := s.exited
+
+	s.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	select {
+	case <-exited:
+		return nil
+	default:
+	}
+
+	slog.Debug("Stopping local server", "script", s.script, "pid", cmd.Process.Pid)
+
+	// SIGTERM is not supported on Windows in which case fall through to Kill below
+
+	err := cmd.Process.Signal(syscall.SIGTERM)
+
+	if err == nil {
+
+		select {
+		case <-exited:
+			return nil
+		case <-ctx.Done():
+		case <-time.After(LOCAL_SERVER_STOP_TIMEOUT):
+		}
+	}
+
+	err = cmd.Process.Kill()
+
+	if err != nil {
+		return fmt.Errorf("Failed to kill local server, %w", err)
+	}
+
+	<-exited
+	return nil
+}
+
+func (s *LocalServer) address() string {
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+func (s *LocalServer) start(ctx context.Context) error {
+
+	args := []string{
+		s.script,
+	}
+
+	args = append(args, s.args...)
+	args = append(args, "--host", s.host, "--port", strconv.Itoa(s.port))
+
+	// Note: We do not use exec.CommandContext because the server needs to outlive 'ctx'
+
+	cmd := exec.Command(s.python, args...)
+
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return fmt.Errorf("Failed to create stdout pipe, %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+
+	if err != nil {
+		return fmt.Errorf("Failed to create stderr pipe, %w", err)
+	}
+
+	err = cmd.Start()
+
+	if err != nil {
+		return fmt.Errorf("Failed to start local server, %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	exited := make(chan struct{})
+	ready := new(atomic.Bool)
+
+	slog.Info("Started local server", "script", s.script, "pid", pid, "address", s.address())
+
+	logs := new(sync.WaitGroup)
+	logs.Add(2)
+
+	go s.relay(logs, stdout, pid, "stdout")
+	go s.relay(logs, stderr, pid, "stderr")
+
+	go func() {
+
+		// Wait for the pipes to be drained before calling Wait, as required by StdoutPipe and StderrPipe
+		logs.Wait()
+
+		err := cmd.Wait()
+		close(exited)
+
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+
+		if closed {
+			slog.Debug("Local server stopped", "script", s.script, "pid", pid)
+			return
+		}
+
+		// Servers which never became ready are dealt with by whatever called start()
+
+		if !ready.Load() {
+			return
+		}
+
+		slog.Warn("Local server exited unexpectedly", "script", s.script, "pid", pid, "error", err)
+
+		if s.restart {
+			s.restartLoop()
+		}
+	}()
+
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+		cmd.Process.Kill()
+		return fmt.Errorf("Local server has been closed")
+	}
+
+	s.cmd = cmd
+	s.exited = exited
+	s.mu.Unlock()
+
+	err = s.waitReady(ctx, exited)
+
+	if err != nil {
+		cmd.Process.Kill()
+		<-exited
+		return err
+	}
+
+	ready.Store(true)
+	return nil
+}
+
+func (s *LocalServer) restartLoop() {
+
+	ctx := context.Background()
+	backoff := time.Second
+
+	for {
+
+		time.Sleep(backoff)
+
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		slog.Info("Restarting local server", "script", s.script)
+
+		err := s.start(ctx)
+
+		if err == nil {
+			return
+		}
+
+		slog.Error("Failed to restart local server", "script", s.script, "error", err)
+
+		backoff = min(backoff*2, LOCAL_SERVER_MAX_BACKOFF)
+	}
+}
+
+func (s *LocalServer) waitReady(ctx context.Context, exited chan struct{}) error {
+
+	ready_ctx, cancel := context.WithTimeout(ctx, s.ready_timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	dialer := &net.Dialer{
+		Timeout: time.Second,
+	}
+
+	for {
+
+		select {
+		case <-ready_ctx.Done():
+			return fmt.Errorf("Local server failed to become ready, %w", ready_ctx.Err())
+		case <-exited:
+			return fmt.Errorf("Local server exited before becoming ready")
+		case <-ticker.C:
+
+			conn, err := dialer.DialContext(ready_ctx, "tcp", s.address())
+
+			if err != nil {
+				continue
+			}
+
+			conn.Close()
+
+			slog.Debug("Local server is ready", "script", s.script, "address", s.address())
+			return nil
+		}
+	}
+}
+
+func (s *LocalServer) relay(wg *sync.WaitGroup, r io.Reader, pid int, stream string) {
+
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		slog.Debug(scanner.Text(), "script", filepath.Base(s.script), "pid", pid, "stream", stream)
+	}
+
+	// Make sure the pipe is always drained so the server never blocks writing to it
+
+	io.Copy(io.Discard, r)
+}
+
+func freePort() (int, error) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,92 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const testLocalServerScript string = `# go-embeddings-scripts-version: 2
+import argparse
+import json
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+parser = argparse.ArgumentParser()
+parser.add_argument("--host", default="localhost")
+parser.add_argument("--port", type=int, default=5000)
+args = parser.parse_args()
+
+class Handler(BaseHTTPRequestHandler):
+
+    def do_POST(self):
+        self.rfile.read(int(self.headers["Content-Length"]))
+        body = json.dumps({"embeddings": [0.1, 0.2, 0.3], "model": "test"}).encode("utf-8")
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.end_headers()
+        self.wfile.write(body)
+
+HTTPServer((args.host, args.port), Handler).serve_forever()
+`
+
+func TestLocalServer(t *testing.T) {
+
+	ctx := context.Background()
+
+	python, err := exec.LookPath("python3")
+
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+
+	script := filepath.Join(t.TempDir(), "server.py")
+
+	err = os.WriteFile(script, []byte(testLocalServerScript), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write server script, %v", err)
+	}
+
+	q := url.Values{}
+	q.Set("launch", python)
+	q.Set("script", script)
+	q.Set("launch-timeout", "30")
+
+	uri := fmt.Sprintf("siglip-client://?%s", q.Encode())
+
+	emb, err := NewEmbedder32(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if len(rsp.Embeddings()) != 3 {
+		t.Fatalf("Unexpected embeddings length: %d", len(rsp.Embeddings()))
+	}
+
+	err = CloseEmbedder(ctx, emb)
+
+	if err != nil {
+		t.Fatalf("Failed to close embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected request to fail after embedder was closed")
+	}
+}
@@ -0,0 +1,311 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The value used to replace secrets in URIs and headers.
+const REDACTED string = "REDACTED"
+
+// Words in query parameter and header names (compared case-insensitively and split on "-" and "_") whose values are considered secret.
+var redacted_words = []string{
+	"key",
+	"apikey",
+	"token",
+	"secret",
+	"password",
+	"passwd",
+	"auth",
+	"authorization",
+	"credential",
+	"credentials",
+	"signature",
+	"cookie",
+}
+
+// Query parameters, defined by this package, which contain a redacted word but whose values are not secret.
+var unredacted_names = []string{
+	"chars-per-token",
+}
+
+// Query parameters whose values are themselves URIs which may contain secrets.
+var nested_uri_names = []string{
+	"client-uri",
+	"server-uri",
+}
+
+// RedactURI returns a copy of 'uri' with any password and the values of any query parameters which look like
+// secrets (for example `api-key` or `token`) replaced with "REDACTED". Nested `client-uri` and `server-uri`
+// parameters are redacted as well. If 'uri' can not be parsed the string "invalid-uri" is returned.
+func RedactURI(uri string) string {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return "invalid-uri"
+	}
+
+	if u.User != nil {
+
+		if _, has_password := u.User.Password(); has_password {
+			u.User = url.UserPassword(u.User.Username(), REDACTED)
+		}
+	}
+
+	if u.RawQuery != "" {
+
+		q := u.Query()
+
+		for k, values := range q {
+
+			for idx, v := range values {
+
+				switch {
+				case isRedactedName(k):
+					values[idx] = REDACTED
+				case isNestedURIName(k):
+					values[idx] = RedactURI(v)
+				}
+			}
+		}
+
+		u.RawQuery = q.Encode()
+	}
+
+	redacted := u.String()
+
+	// url.URL omits the "//" for URIs without a host (for example "ollama://?model=...")
+
+	prefix := u.Scheme + "://"
+
+	if u.Scheme != "" && strings.HasPrefix(uri, prefix) && !strings.HasPrefix(redacted, prefix) {
+		redacted = prefix + strings.TrimPrefix(redacted, u.Scheme+":")
+	}
+
+	return redacted
+}
+
+// RedactHeaders returns a copy of 'h' with the values of any headers which look like secrets (for example `Authorization`,
+// `Cookie` or `X-Api-Key`) replaced with "REDACTED".
+func RedactHeaders(h http.Header) http.Header {
+
+	redacted := h.Clone()
+
+	for k, values := range redacted {
+
+		if !isRedactedName(k) {
+			continue
+		}
+
+		for idx := range values {
+			values[idx] = REDACTED
+		}
+	}
+
+	return redacted
+}
+
+func isRedactedName(name string) bool {
+
+	name = strings.ToLower(name)
+
+	if slices.Contains(unredacted_names, name) {
+		return false
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+
+	for _, w := range words {
+
+		if slices.Contains(redacted_words, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isNestedURIName(name string) bool {
+	return slices.Contains(nested_uri_names, name)
+}
+
+// logHTTPRequest logs (at the debug level) the endpoint, status, latency and sizes of an HTTP request to an embeddings service
+// which was started at 't1'. It should be called once the response body has been read so that the latency includes reading the
+// body and 'response_size' is the number of bytes actually read. 'status' is 0 if the request failed without a response.
+func logHTTPRequest(ctx context.Context, req *http.Request, status int, t1 time.Time, request_size int, response_size int, model string, err error) {
+
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []any{
+		"method", req.Method,
+		"headers", RedactHeaders(req.Header),
+	}
+
+	if status != 0 {
+		attrs = append(attrs, "status", status)
+	}
+
+	logClientRequest(ctx, "HTTP", req.URL.String(), t1, request_size, response_size, model, err, attrs...)
+}
+
+// logClientRequest logs (at the debug level) the endpoint, latency and sizes of a request, using 'protocol' (for example "gRPC"
+// or "stdio"), to an embeddings service which was started at 't1'. A negative 'response_size' means the size of the response is
+// not known. Any additional key/value pairs in 'attrs' are included in the log message.
+func logClientRequest(ctx context.Context, protocol string, endpoint string, t1 time.Time, request_size int, response_size int, model string, err error, attrs ...any) {
+
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs = append([]any{
+		"endpoint", RedactURI(endpoint),
+		"latency", time.Since(t1),
+		"request_bytes", request_size,
+	}, attrs...)
+
+	if response_size >= 0 {
+		attrs = append(attrs, "response_bytes", response_size)
+	}
+
+	if model != "" {
+		attrs = append(attrs, "model", model)
+	}
+
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+
+	slog.DebugContext(ctx, fmt.Sprintf("Embeddings %s request", protocol), attrs...)
+}
+
+// LogEmbedder implements the `Embedder` interface by logging each request sent to another `Embedder` instance
+// using the default `log/slog` logger.
+type LogEmbedder[T Float] struct {
+	Embedder[T]
+	embedder Embedder[T]
+	label    string
+	level    slog.Level
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "log", NewLogEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "log32", NewLogEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "log64", NewLogEmbedder[float64])
+}
+
+// NewLogEmbedder creates a new `LogEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	log://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `level` – The level at which successful requests are logged. Valid options are "debug", "info" and "warn". Default is "debug".
+// Failed requests are always logged at the "warn" level (or higher).
+func NewLogEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &LogEmbedder[T]{
+		embedder: emb,
+		label:    RedactURI(client_uri),
+		level:    slog.LevelDebug,
+	}
+
+	if q.Has("level") {
+
+		switch q.Get("level") {
+		case "debug":
+			e.level = slog.LevelDebug
+		case "info":
+			e.level = slog.LevelInfo
+		case "warn":
+			e.level = slog.LevelWarn
+		default:
+			return nil, fmt.Errorf("Invalid ?level= parameter")
+		}
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings passes 'req' to the underlying embedder and logs the result.
+func (e *LogEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "text", e.embedder.TextEmbeddings)
+}
+
+// ImageEmbeddings passes 'req' to the underlying embedder and logs the result.
+func (e *LogEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "image", e.embedder.ImageEmbeddings)
+}
+
+// Close closes the underlying embedder.
+func (e *LogEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *LogEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, modality string, fn func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	t1 := time.Now()
+
+	rsp, err := fn(ctx, req)
+
+	attrs := []any{
+		"embedder", e.label,
+		"id", req.Id,
+		"modality", modality,
+		"body_size", len(req.Body),
+		"latency", time.Since(t1),
+	}
+
+	if req.Task != "" {
+		attrs = append(attrs, "task", req.Task)
+	}
+
+	if err != nil {
+
+		if req.Model != "" {
+			attrs = append(attrs, "model", req.Model)
+		}
+
+		attrs = append(attrs, "error", err)
+		slog.Log(ctx, max(e.level, slog.LevelWarn), "Failed to derive embeddings", attrs...)
+		return nil, err
+	}
+
+	attrs = append(attrs, "model", rsp.Model(), "dimensions", len(rsp.Embeddings()))
+	slog.Log(ctx, e.level, "Derived embeddings", attrs...)
+
+	return rsp, nil
+}
@@ -0,0 +1,368 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// MatryoshkaModel defines a family of models trained with Matryoshka Representation Learning (MRL) whose
+// embeddings can be truncated to a smaller number of dimensions.
+type MatryoshkaModel struct {
+	// The unique name of the model family.
+	Name string
+	// A regular expression used to match model names to the family.
+	Pattern *regexp.Regexp
+	// The dimensions the model was trained to support. If empty embeddings may be truncated to any smaller dimension.
+	Dimensions []int
+}
+
+// TruncatedEmbeddingsResponse is an `EmbeddingsResponse` implementation which records the original
+// dimensions of embeddings that have been truncated.
+type TruncatedEmbeddingsResponse[T Float] struct {
+	CommonEmbeddingsResponse[T]
+	OriginalDimensions  int `json:"original_dimensions"`
+	TruncatedDimensions int `json:"truncated_dimensions"`
+}
+
+// TruncateEmbedder implements the `Embedder` interface by truncating (and optionally renormalizing) the embeddings
+// derived by another `Embedder` instance.
+type TruncateEmbedder[T Float] struct {
+	Embedder[T]
+	embedder   Embedder[T]
+	dimensions int
+	normalize  bool
+	force      bool
+	// The model declared by the client URI was checked when the embedder was created.
+	checked bool
+}
+
+var matryoshka_models = make([]*MatryoshkaModel, 0)
+var matryoshka_models_mu = new(sync.RWMutex)
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "truncate", NewTruncateEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "truncate32", NewTruncateEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "truncate64", NewTruncateEmbedder[float64])
+
+	builtin := []*MatryoshkaModel{
+		{
+			// https://huggingface.co/nomic-ai/nomic-embed-text-v1.5
+			Name:       "nomic-embed-text-v1.5",
+			Pattern:    regexp.MustCompile(`(?i)nomic-embed-text(-v1\.5|:|$)`),
+			Dimensions: []int{768, 512, 256, 128, 64},
+		},
+		{
+			// https://ai.google.dev/gemma/docs/embeddinggemma/model_card
+			Name:       "embeddinggemma",
+			Pattern:    regexp.MustCompile(`(?i)embeddinggemma`),
+			Dimensions: []int{768, 512, 256, 128},
+		},
+		{
+			// https://huggingface.co/mixedbread-ai/mxbai-embed-large-v1
+			Name:    "mxbai-embed-large",
+			Pattern: regexp.MustCompile(`(?i)mxbai-embed-large`),
+		},
+		{
+			// https://huggingface.co/Snowflake/snowflake-arctic-embed-m-v1.5
+			Name:       "snowflake-arctic-embed-m-v1.5",
+			Pattern:    regexp.MustCompile(`(?i)snowflake-arctic-embed-m-v1\.5`),
+			Dimensions: []int{768, 256},
+		},
+		{
+			// https://huggingface.co/jinaai/jina-embeddings-v3
+			Name:       "jina-embeddings-v3",
+			Pattern:    regexp.MustCompile(`(?i)jina-embeddings-v3`),
+			Dimensions: []int{1024, 768, 512, 256, 128, 64, 32},
+		},
+		{
+			// https://huggingface.co/Qwen/Qwen3-Embedding-0.6B
+			Name:    "qwen3-embedding",
+			Pattern: regexp.MustCompile(`(?i)qwen3-embedding`),
+		},
+		{
+			// https://platform.openai.com/docs/guides/embeddings
+			Name:    "text-embedding-3",
+			Pattern: regexp.MustCompile(`(?i)text-embedding-3-(small|large)`),
+		},
+	}
+
+	for _, m := range builtin {
+
+		err := RegisterMatryoshkaModel(ctx, m)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterMatryoshkaModel registers 'm' so that it can be retrieved by the `MatryoshkaModelForModel` method.
+// Registering a model with the same name as an existing model replaces it.
+func RegisterMatryoshkaModel(ctx context.Context, m *MatryoshkaModel) error {
+
+	if m.Name == "" {
+		return fmt.Errorf("Matryoshka model is missing a name")
+	}
+
+	if m.Pattern == nil {
+		return fmt.Errorf("Matryoshka model '%s' is missing a pattern", m.Name)
+	}
+
+	matryoshka_models_mu.Lock()
+	defer matryoshka_models_mu.Unlock()
+
+	for idx, existing := range matryoshka_models {
+
+		if existing.Name == m.Name {
+			matryoshka_models[idx] = m
+			return nil
+		}
+	}
+
+	matryoshka_models = append(matryoshka_models, m)
+	return nil
+}
+
+// MatryoshkaModelForModel returns the first registered `MatryoshkaModel` whose pattern matches 'model'.
+func MatryoshkaModelForModel(model string) (*MatryoshkaModel, bool) {
+
+	if model == "" {
+		return nil, false
+	}
+
+	matryoshka_models_mu.RLock()
+	defer matryoshka_models_mu.RUnlock()
+
+	for _, m := range matryoshka_models {
+
+		if m.Pattern.MatchString(model) {
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+// SupportsDimensions returns a boolean value indicating whether embeddings for the model can be truncated to 'dimensions'.
+func (m *MatryoshkaModel) SupportsDimensions(dimensions int) bool {
+
+	if len(m.Dimensions) == 0 {
+		return dimensions > 0
+	}
+
+	return slices.Contains(m.Dimensions, dimensions)
+}
+
+// checkMatryoshkaDimensions returns an error if 'model' is not known to support Matryoshka truncation to 'dimensions'.
+func checkMatryoshkaDimensions(model string, dimensions int) error {
+
+	m, exists := MatryoshkaModelForModel(model)
+
+	if !exists {
+		return fmt.Errorf("Model '%s' is not known to support Matryoshka truncation", model)
+	}
+
+	if !m.SupportsDimensions(dimensions) {
+		return fmt.Errorf("Model '%s' does not support truncation to %d dimensions, supported dimensions are %v", model, dimensions, m.Dimensions)
+	}
+
+	return nil
+}
+
+// NewTruncateEmbedder creates a new `TruncateEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	truncate://?client-uri={CLIENT_URI}&dimensions={DIMENSIONS}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `dimensions` – The number of dimensions to truncate embeddings to. Required.
+// * `normalize` – A boolean flag indicating whether truncated embeddings should be renormalized to unit length. Default is true.
+// * `force` – A boolean flag indicating that embeddings should be truncated even if the model is not known to support Matryoshka truncation. Default is false.
+func NewTruncateEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	if !q.Has("dimensions") {
+		return nil, fmt.Errorf("Missing ?dimensions= parameter")
+	}
+
+	dimensions, err := strconv.Atoi(q.Get("dimensions"))
+
+	if err != nil || dimensions < 1 {
+		return nil, fmt.Errorf("Invalid ?dimensions= parameter")
+	}
+
+	normalize := true
+
+	if q.Has("normalize") {
+
+		v, err := strconv.ParseBool(q.Get("normalize"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?normalize= parameter, %w", err)
+		}
+
+		normalize = v
+	}
+
+	force := false
+
+	if q.Has("force") {
+
+		v, err := strconv.ParseBool(q.Get("force"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?force= parameter, %w", err)
+		}
+
+		force = v
+	}
+
+	client_uri := q.Get("client-uri")
+
+	// If the client URI declares a model check it now rather than waiting for the first response
+
+	checked := false
+
+	if !force {
+
+		client_u, err := url.Parse(client_uri)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse ?client-uri= parameter, %w", err)
+		}
+
+		client_model := client_u.Query().Get("model")
+
+		if client_model != "" {
+
+			err := checkMatryoshkaDimensions(client_model, dimensions)
+
+			if err != nil {
+				return nil, err
+			}
+
+			checked = true
+		}
+	}
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &TruncateEmbedder[T]{
+		embedder:   emb,
+		dimensions: dimensions,
+		normalize:  normalize,
+		force:      force,
+		checked:    checked,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives embeddings for 'req' using the underlying embedder and returns a `TruncatedEmbeddingsResponse` instance.
+func (e *TruncateEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	rsp, err := e.embedder.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.truncate(rsp)
+}
+
+// ImageEmbeddings derives embeddings for 'req' using the underlying embedder and returns a `TruncatedEmbeddingsResponse` instance.
+func (e *TruncateEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	rsp, err := e.embedder.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.truncate(rsp)
+}
+
+// Close closes the underlying embedder.
+func (e *TruncateEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *TruncateEmbedder[T]) truncate(rsp EmbeddingsResponse[T]) (EmbeddingsResponse[T], error) {
+
+	// If the client URI did not declare a model check the model reported by the response instead. Once a declared
+	// model has been checked the response is trusted since backends may report the same model using a different name.
+
+	if !e.force && !e.checked {
+
+		err := checkMatryoshkaDimensions(rsp.Model(), e.dimensions)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	truncated, err := TruncateEmbeddings(rsp.Embeddings(), e.dimensions, e.normalize)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tr_rsp := &TruncatedEmbeddingsResponse[T]{
+		CommonEmbeddingsResponse: CommonEmbeddingsResponse[T]{
+			CommonId:         rsp.Id(),
+			CommonEmbeddings: truncated,
+			CommonModel:      rsp.Model(),
+			CommonCreated:    rsp.Created(),
+			CommonPrecision:  rsp.Precision(),
+		},
+		OriginalDimensions:  len(rsp.Embeddings()),
+		TruncatedDimensions: len(truncated),
+	}
+
+	return tr_rsp, nil
+}
+
+// TruncateEmbeddings returns the first 'dimensions' elements of 'vector', renormalized to unit length if 'normalize' is true.
+// It is an error if 'vector' has fewer than 'dimensions' elements.
+func TruncateEmbeddings[T Float](vector []T, dimensions int, normalize bool) ([]T, error) {
+
+	if dimensions < 1 {
+		return nil, fmt.Errorf("Invalid dimensions")
+	}
+
+	if len(vector) < dimensions {
+		return nil, fmt.Errorf("Can not truncate embeddings with %d dimensions to %d dimensions", len(vector), dimensions)
+	}
+
+	if normalize {
+		return Normalize(vector[0:dimensions]), nil
+	}
+
+	truncated := make([]T, dimensions)
+	copy(truncated, vector[0:dimensions])
+
+	return truncated, nil
+}
@@ -0,0 +1,121 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestTruncateEmbeddings(t *testing.T) {
+
+	v := []float32{3, 4, 12, 84}
+
+	truncated, err := TruncateEmbeddings(v, 2, true)
+
+	if err != nil {
+		t.Fatalf("Failed to truncate embeddings, %v", err)
+	}
+
+	if len(truncated) != 2 || math.Abs(float64(truncated[0])-0.6) > 1e-6 || math.Abs(float64(truncated[1])-0.8) > 1e-6 {
+		t.Fatalf("Unexpected truncated embeddings, %v", truncated)
+	}
+
+	_, err = TruncateEmbeddings(v, 8, true)
+
+	if err == nil {
+		t.Fatalf("Expected truncating to more dimensions than available to fail")
+	}
+}
+
+func TestTruncateEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	emb, err := NewEmbedder32(ctx, "truncate://?client-uri=testing://%3Fdimensions=768%26model=ollama/embeddinggemma&dimensions=256")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if rsp.Dimensions() != 256 {
+		t.Fatalf("Unexpected dimensions: %d", rsp.Dimensions())
+	}
+
+	tr_rsp, ok := rsp.(*TruncatedEmbeddingsResponse[float32])
+
+	if !ok {
+		t.Fatalf("Unexpected response type")
+	}
+
+	if tr_rsp.OriginalDimensions != 768 || tr_rsp.TruncatedDimensions != 256 {
+		t.Fatalf("Unexpected original (%d) or truncated (%d) dimensions", tr_rsp.OriginalDimensions, tr_rsp.TruncatedDimensions)
+	}
+
+	// Unsupported dimensions for a model declared by the client URI fail before any requests are sent
+
+	_, err = NewEmbedder32(ctx, "truncate://?client-uri=testing://%3Fdimensions=768%26model=ollama/embeddinggemma&dimensions=300")
+
+	if err == nil {
+		t.Fatalf("Expected unsupported dimensions to fail")
+	}
+
+	_, err = NewEmbedder32(ctx, "truncate://?client-uri=testing://%3Fdimensions=768%26model=all-minilm&dimensions=256")
+
+	if err == nil {
+		t.Fatalf("Expected unknown model declared by client URI to fail")
+	}
+
+	// Once a model declared by the client URI has been checked the model reported by responses is not
+
+	emb, err = NewEmbedder32(ctx, "truncate://?client-uri=testing://%3Fdimensions=768%26model=ollama/embeddinggemma%26reported-model=embeddinggemma:latest&dimensions=256")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings for differently reported model, %v", err)
+	}
+
+	// Unknown model, not declared by the client URI, with and without ?force=
+
+	emb, err = NewEmbedder32(ctx, "truncate://?client-uri=testing://%3Fdimensions=8&dimensions=4")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected unknown model to fail")
+	}
+
+	emb, err = NewEmbedder32(ctx, "truncate://?client-uri=testing://%3Fdimensions=8&dimensions=4&force=true")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	rsp, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive forced embeddings, %v", err)
+	}
+
+	if rsp.Dimensions() != 4 {
+		t.Fatalf("Unexpected dimensions: %d", rsp.Dimensions())
+	}
+}
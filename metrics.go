@@ -0,0 +1,392 @@
+package embeddings
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The upper bounds, in seconds, of the buckets used for request latency histograms.
+var MetricsDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// The upper bounds, in bytes, of the buckets used for request size histograms.
+var MetricsBytesBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216}
+
+// DefaultMetrics is the `Metrics` instance used by `MetricsEmbedder`. It is published using `expvar` as "embeddings".
+var DefaultMetrics = NewMetrics()
+
+// MetricsHistogram defines a snapshot of a histogram.
+type MetricsHistogram struct {
+	// The upper bound of each bucket.
+	Buckets []float64 `json:"buckets"`
+	// The cumulative number of observations less than or equal to each bucket's upper bound.
+	Counts []uint64 `json:"counts"`
+	// The total number of observations.
+	Count uint64 `json:"count"`
+	// The sum of all observations.
+	Sum float64 `json:"sum"`
+}
+
+// MetricsSeries defines a snapshot of the metrics for requests with the same scheme, model and modality.
+type MetricsSeries struct {
+	// The scheme (or label) of the underlying embedder.
+	Scheme string `json:"scheme"`
+	// The model used to derive embeddings.
+	Model string `json:"model"`
+	// The modality of the requests ("text" or "image").
+	Modality string `json:"modality"`
+	// The total number of requests.
+	Requests uint64 `json:"requests"`
+	// The total number of requests which failed.
+	Errors uint64 `json:"errors"`
+	// The latency of requests, in seconds.
+	Duration *MetricsHistogram `json:"duration_seconds"`
+	// The size of request bodies, in bytes.
+	RequestBytes *MetricsHistogram `json:"request_bytes"`
+	// The number of dimensions of the most recent embeddings.
+	Dimensions int `json:"dimensions"`
+}
+
+// Metrics records request counts, latencies, payload sizes, output dimensions and errors for embeddings requests.
+type Metrics struct {
+	mu     sync.Mutex
+	series map[metricsKey]*metricsSeries
+}
+
+type metricsKey struct {
+	scheme   string
+	model    string
+	modality string
+}
+
+type metricsSeries struct {
+	requests      uint64
+	errors        uint64
+	duration      *metricsHistogram
+	request_bytes *metricsHistogram
+	dimensions    int
+}
+
+type metricsHistogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewMetrics returns a new, empty `Metrics` instance.
+func NewMetrics() *Metrics {
+
+	m := &Metrics{
+		series: make(map[metricsKey]*metricsSeries),
+	}
+
+	return m
+}
+
+// Observe records a request for 'modality' embeddings using 'scheme' and 'model', with a body of 'size' bytes, which took 'duration'
+// and returned embeddings with 'dimensions' dimensions, or failed with 'err'.
+func (m *Metrics) Observe(scheme string, model string, modality string, size int, duration time.Duration, dimensions int, err error) {
+
+	k := metricsKey{
+		scheme:   scheme,
+		model:    model,
+		modality: modality,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.series[k]
+
+	if !exists {
+
+		s = &metricsSeries{
+			duration:      newMetricsHistogram(MetricsDurationBuckets),
+			request_bytes: newMetricsHistogram(MetricsBytesBuckets),
+		}
+
+		m.series[k] = s
+	}
+
+	s.requests += 1
+	s.duration.observe(duration.Seconds())
+	s.request_bytes.observe(float64(size))
+
+	if err != nil {
+		s.errors += 1
+	} else {
+		s.dimensions = dimensions
+	}
+}
+
+// Snapshot returns a copy of the current metrics, sorted by scheme, model and modality.
+func (m *Metrics) Snapshot() []*MetricsSeries {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]*MetricsSeries, 0, len(m.series))
+
+	for k, s := range m.series {
+
+		snapshot = append(snapshot, &MetricsSeries{
+			Scheme:       k.scheme,
+			Model:        k.model,
+			Modality:     k.modality,
+			Requests:     s.requests,
+			Errors:       s.errors,
+			Duration:     s.duration.snapshot(),
+			RequestBytes: s.request_bytes.snapshot(),
+			Dimensions:   s.dimensions,
+		})
+	}
+
+	slices.SortFunc(snapshot, func(a *MetricsSeries, b *MetricsSeries) int {
+		return strings.Compare(a.Scheme+"\x00"+a.Model+"\x00"+a.Modality, b.Scheme+"\x00"+b.Model+"\x00"+b.Modality)
+	})
+
+	return snapshot
+}
+
+// Handler returns an `http.Handler` which writes the current metrics using the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+
+	fn := func(rsp http.ResponseWriter, req *http.Request) {
+		rsp.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(rsp)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// WritePrometheus writes the current metrics to 'wr' using the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(wr io.Writer) error {
+
+	snapshot := m.Snapshot()
+
+	var sb strings.Builder
+
+	counter := func(name string, help string, value func(*MetricsSeries) uint64) {
+
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+		for _, s := range snapshot {
+			fmt.Fprintf(&sb, "%s{%s} %d\n", name, prometheusLabels(s), value(s))
+		}
+	}
+
+	histogram := func(name string, help string, value func(*MetricsSeries) *MetricsHistogram) {
+
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+		for _, s := range snapshot {
+
+			labels := prometheusLabels(s)
+			h := value(s)
+
+			for i, le := range h.Buckets {
+				fmt.Fprintf(&sb, "%s_bucket{%s,le=\"%g\"} %d\n", name, labels, le, h.Counts[i])
+			}
+
+			fmt.Fprintf(&sb, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.Count)
+			fmt.Fprintf(&sb, "%s_sum{%s} %g\n", name, labels, h.Sum)
+			fmt.Fprintf(&sb, "%s_count{%s} %d\n", name, labels, h.Count)
+		}
+	}
+
+	counter("embeddings_requests_total", "The total number of embeddings requests.", func(s *MetricsSeries) uint64 { return s.Requests })
+	counter("embeddings_errors_total", "The total number of embeddings requests which failed.", func(s *MetricsSeries) uint64 { return s.Errors })
+
+	histogram("embeddings_request_duration_seconds", "The latency of embeddings requests.", func(s *MetricsSeries) *MetricsHistogram { return s.Duration })
+	histogram("embeddings_request_bytes", "The size of embeddings request bodies.", func(s *MetricsSeries) *MetricsHistogram { return s.RequestBytes })
+
+	fmt.Fprintf(&sb, "# HELP embeddings_dimensions The number of dimensions of the most recent embeddings.\n# TYPE embeddings_dimensions gauge\n")
+
+	for _, s := range snapshot {
+		fmt.Fprintf(&sb, "embeddings_dimensions{%s} %d\n", prometheusLabels(s), s.Dimensions)
+	}
+
+	_, err := io.WriteString(wr, sb.String())
+	return err
+}
+
+// MetricsHandler returns an `http.Handler` which writes the metrics recorded by `MetricsEmbedder` instances using the Prometheus text exposition format.
+func MetricsHandler() http.Handler {
+	return DefaultMetrics.Handler()
+}
+
+func newMetricsHistogram(buckets []float64) *metricsHistogram {
+
+	h := &metricsHistogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+
+	return h
+}
+
+func (h *metricsHistogram) observe(v float64) {
+
+	h.count += 1
+	h.sum += v
+
+	for i, le := range h.buckets {
+
+		if v <= le {
+			h.counts[i] += 1
+			break
+		}
+	}
+}
+
+// snapshot returns a copy of the histogram with cumulative bucket counts.
+func (h *metricsHistogram) snapshot() *MetricsHistogram {
+
+	s := &MetricsHistogram{
+		Buckets: slices.Clone(h.buckets),
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+
+	var total uint64
+
+	for i, c := range h.counts {
+		total += c
+		s.Counts[i] = total
+	}
+
+	return s
+}
+
+func prometheusLabels(s *MetricsSeries) string {
+
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return fmt.Sprintf(`scheme="%s",model="%s",modality="%s"`, escape.Replace(s.Scheme), escape.Replace(s.Model), escape.Replace(s.Modality))
+}
+
+// MetricsEmbedder implements the `Embedder` interface by recording metrics for each request sent to another `Embedder` instance.
+// Metrics are recorded in `DefaultMetrics`.
+type MetricsEmbedder[T Float] struct {
+	Embedder[T]
+	embedder Embedder[T]
+	metrics  *Metrics
+	scheme   string
+	model    string
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "metrics", NewMetricsEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "metrics32", NewMetricsEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "metrics64", NewMetricsEmbedder[float64])
+
+	expvar.Publish("embeddings", expvar.Func(func() any {
+		return DefaultMetrics.Snapshot()
+	}))
+}
+
+// NewMetricsEmbedder creates a new `MetricsEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	metrics://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `label` – The value of the "scheme" label for metrics. Default is the scheme of `client-uri`.
+func NewMetricsEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	client_u, err := url.Parse(client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse client URI, %w", err)
+	}
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &MetricsEmbedder[T]{
+		embedder: emb,
+		metrics:  DefaultMetrics,
+		scheme:   client_u.Scheme,
+		model:    client_u.Query().Get("model"),
+	}
+
+	if q.Has("label") {
+		e.scheme = q.Get("label")
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings passes 'req' to the underlying embedder and records metrics for the request.
+func (e *MetricsEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(req, "text", func() (EmbeddingsResponse[T], error) {
+		return e.embedder.TextEmbeddings(ctx, req)
+	})
+}
+
+// ImageEmbeddings passes 'req' to the underlying embedder and records metrics for the request.
+func (e *MetricsEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(req, "image", func() (EmbeddingsResponse[T], error) {
+		return e.embedder.ImageEmbeddings(ctx, req)
+	})
+}
+
+// Close closes the underlying embedder.
+func (e *MetricsEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *MetricsEmbedder[T]) embeddings(req *EmbeddingsRequest, modality string, fn func() (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	// The model label is derived from the request, or the client URI, and never from the response so that
+	// successes and failures for the same backend are recorded in the same series. Responses may report
+	// a more specific model name (for example "nomic-embed-text:latest" rather than "nomic-embed-text").
+
+	model := e.model
+
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	t1 := time.Now()
+
+	rsp, err := fn()
+
+	duration := time.Since(t1)
+	dimensions := 0
+
+	if err == nil {
+		dimensions = len(rsp.Embeddings())
+	}
+
+	e.metrics.Observe(e.scheme, model, modality, len(req.Body), duration, dimensions, err)
+	return rsp, err
+}
@@ -0,0 +1,145 @@
+package embeddings
+
+import (
+	"context"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "metrics://?client-uri=testing://%3Fmodel=metrics-test%26dimensions=8&label=metrics-test")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	for i := 0; i < 3; i++ {
+
+		_, err := emb.TextEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive embeddings, %v", err)
+		}
+	}
+
+	var series *MetricsSeries
+
+	for _, s := range DefaultMetrics.Snapshot() {
+
+		if s.Scheme == "metrics-test" && s.Modality == "text" {
+			series = s
+		}
+	}
+
+	if series == nil {
+		t.Fatalf("Missing metrics for embedder")
+	}
+
+	if series.Model != "metrics-test" || series.Requests != 3 || series.Errors != 0 || series.Dimensions != 8 {
+		t.Fatalf("Unexpected metrics: %+v", series)
+	}
+
+	if series.RequestBytes.Sum != 33 {
+		t.Fatalf("Unexpected request bytes: %f", series.RequestBytes.Sum)
+	}
+
+	if expvar.Get("embeddings") == nil {
+		t.Fatalf("Metrics not published using expvar")
+	}
+}
+
+func TestMetricsEmbedderModelLabel(t *testing.T) {
+
+	ctx := context.Background()
+
+	// The backend reports a different model name from the one requested, and fails the first request
+
+	emb, err := NewEmbedder32(ctx, "metrics://?client-uri=testing://%3Fmodel=nomic-embed-text:latest%26error=retryable%26failures=1&label=metrics-label-test")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Model: "nomic-embed-text",
+		Body:  []byte("Hello world"),
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected first request to fail")
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	count := 0
+
+	for _, s := range DefaultMetrics.Snapshot() {
+
+		if s.Scheme != "metrics-label-test" {
+			continue
+		}
+
+		count += 1
+
+		if s.Model != "nomic-embed-text" || s.Requests != 2 || s.Errors != 1 {
+			t.Fatalf("Unexpected metrics: %+v", s)
+		}
+	}
+
+	if count != 1 {
+		t.Fatalf("Expected successes and failures to be recorded in 1 series, got %d", count)
+	}
+}
+
+func TestMetricsPrometheus(t *testing.T) {
+
+	m := NewMetrics()
+
+	m.Observe("ollama", "embeddinggemma", "text", 100, 20*time.Millisecond, 768, nil)
+	m.Observe("ollama", "embeddinggemma", "text", 2000, 2*time.Second, 0, &HTTPError{StatusCode: 503})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+
+	m.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+
+	expected := []string{
+		"# TYPE embeddings_requests_total counter",
+		`embeddings_requests_total{scheme="ollama",model="embeddinggemma",modality="text"} 2`,
+		`embeddings_errors_total{scheme="ollama",model="embeddinggemma",modality="text"} 1`,
+		`embeddings_request_duration_seconds_bucket{scheme="ollama",model="embeddinggemma",modality="text",le="0.025"} 1`,
+		`embeddings_request_duration_seconds_bucket{scheme="ollama",model="embeddinggemma",modality="text",le="2.5"} 2`,
+		`embeddings_request_duration_seconds_bucket{scheme="ollama",model="embeddinggemma",modality="text",le="+Inf"} 2`,
+		`embeddings_request_bytes_sum{scheme="ollama",model="embeddinggemma",modality="text"} 2100`,
+		`embeddings_dimensions{scheme="ollama",model="embeddinggemma",modality="text"} 768`,
+	}
+
+	for _, str := range expected {
+
+		if !strings.Contains(body, str) {
+			t.Fatalf("Missing '%s' in output:\n%s", str, body)
+		}
+	}
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Unexpected content type, %s", rec.Header().Get("Content-Type"))
+	}
+}
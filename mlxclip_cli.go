@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -18,6 +17,7 @@ type MLXClipEmbedder[T Float] struct {
 	model_dir     string
 	embeddings_py string
 	precision     string
+	protocol      string
 }
 
 func init() {
@@ -37,13 +37,9 @@ func NewMLXClipEmbedder[T Float](ctx context.Context, uri string) (Embedder[T],
 
 	q := u.Query()
 
-	embeddings_py, err := filepath.Abs(u.Path)
+	// If the path is empty use the script bundled with this package
 
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = os.Stat(embeddings_py)
+	embeddings_py, err := ensureScript(u.Path, "mlxclip_cli.py")
 
 	if err != nil {
 		return nil, err
@@ -96,22 +92,45 @@ func NewMLXClipEmbedder[T Float](ctx context.Context, uri string) (Embedder[T],
 		python = abs_python
 	}
 
+	protocol := COMMAND_LINE_PROTOCOL_STDIO
+
+	if q.Has("protocol") {
+
+		protocol = q.Get("protocol")
+
+		err := validateCommandLineProtocol(protocol)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	e := &MLXClipEmbedder[T]{
 		python:        python,
 		model_dir:     model_dir,
 		embeddings_py: embeddings_py,
 		precision:     precision,
+		protocol:      protocol,
 	}
 
 	return e, nil
 }
 
 func (e *MLXClipEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
-	return e.generate_embeddings(ctx, req, "text", string(req.Body))
+
+	if e.protocol == COMMAND_LINE_PROTOCOL_FILE {
+		return e.generate_embeddings(ctx, req, "text", string(req.Body))
+	}
+
+	return e.generate_embeddings_stdio(ctx, req, "text")
 }
 
 func (e *MLXClipEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
+	if e.protocol != COMMAND_LINE_PROTOCOL_FILE {
+		return e.generate_embeddings_stdio(ctx, req, "image")
+	}
+
 	tmp, err := os.CreateTemp("", "mlxclip.*.img")
 
 	if err != nil {
@@ -163,11 +182,10 @@ func (e *MLXClipEmbedder[T]) generate_embeddings(ctx context.Context, req *Embed
 		tmp.Name(),
 	}
 
-	cmd := exec.CommandContext(ctx, e.python, args...)
-	err = cmd.Run()
+	err = runCommandLine(ctx, e.python, args)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to derive embeddings, %w", err)
+		return nil, err
 	}
 
 	r, err := os.Open(tmp.Name())
@@ -187,6 +205,30 @@ func (e *MLXClipEmbedder[T]) generate_embeddings(ctx context.Context, req *Embed
 		return nil, fmt.Errorf("Failed to unmarshal embeddings, %w (%s)", err, tmp.Name())
 	}
 
+	return e.embeddings_response(req, emb_rsp.Model, emb_rsp.Embeddings), nil
+}
+
+func (e *MLXClipEmbedder[T]) generate_embeddings_stdio(ctx context.Context, req *EmbeddingsRequest, modality string) (EmbeddingsResponse[T], error) {
+
+	args := []string{
+		e.embeddings_py,
+		"--model_dir",
+		e.model_dir,
+		"--stdio",
+	}
+
+	cl_req := newCommandLineEmbeddingsRequest(req, modality)
+	cl_rsp, err := runCommandLineEmbeddings(ctx, e.python, args, cl_req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.embeddings_response(req, cl_rsp.Model, cl_rsp.Embeddings), nil
+}
+
+func (e *MLXClipEmbedder[T]) embeddings_response(req *EmbeddingsRequest, model string, e64 []float64) EmbeddingsResponse[T] {
+
 	now := time.Now()
 	ts := now.Unix()
 
@@ -194,15 +236,15 @@ func (e *MLXClipEmbedder[T]) generate_embeddings(ctx context.Context, req *Embed
 		CommonId:        req.Id,
 		CommonPrecision: e.precision,
 		CommonCreated:   ts,
-		CommonModel:     emb_rsp.Model,
+		CommonModel:     model,
 	}
 
 	switch {
 	case strings.HasSuffix(e.precision, "32"):
-		rsp.CommonEmbeddings = toFloat32Slice[T](AsFloat32(emb_rsp.Embeddings))
+		rsp.CommonEmbeddings = toFloat32Slice[T](AsFloat32(e64))
 	default:
-		rsp.CommonEmbeddings = toFloat64Slice[T](emb_rsp.Embeddings)
+		rsp.CommonEmbeddings = toFloat64Slice[T](e64)
 	}
 
-	return rsp, nil
+	return rsp
 }
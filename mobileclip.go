@@ -16,8 +16,9 @@ import (
 // MobileCLIPEmbedder implements the `Embedder` interface using an MobileCLIP API endpoint to derive embeddings.
 type MobileCLIPEmbedder[T Float] struct {
 	Embedder[T]
-	client    mobileclip.EmbeddingsClient
-	precision string
+	client     mobileclip.EmbeddingsClient
+	client_uri string
+	precision  string
 }
 
 func init() {
@@ -52,8 +53,9 @@ func NewMobileCLIPEmbedder[T Float](ctx context.Context, uri string) (Embedder[T
 	}
 
 	e := &MobileCLIPEmbedder[T]{
-		client:    cl,
-		precision: precision,
+		client:     cl,
+		client_uri: client_uri,
+		precision:  precision,
 	}
 
 	return e, nil
@@ -66,7 +68,11 @@ func (e *MobileCLIPEmbedder[T]) TextEmbeddings(ctx context.Context, req *Embeddi
 		Body:  req.Body,
 	}
 
-	mc_rsp, err := e.client.ComputeTextEmbeddings(ctx, mc_req)
+	t1 := time.Now()
+
+	mc_rsp, err := e.client.ComputeTextEmbeddings(injectTraceMetadata(ctx), mc_req)
+
+	logClientRequest(ctx, "gRPC", e.client_uri, t1, len(req.Body), -1, req.Model, err)
 
 	if err != nil {
 		return nil, err
@@ -83,7 +89,11 @@ func (e *MobileCLIPEmbedder[T]) ImageEmbeddings(ctx context.Context, req *Embedd
 		Body:  req.Body,
 	}
 
-	mc_rsp, err := e.client.ComputeImageEmbeddings(ctx, mc_req)
+	t1 := time.Now()
+
+	mc_rsp, err := e.client.ComputeImageEmbeddings(injectTraceMetadata(ctx), mc_req)
+
+	logClientRequest(ctx, "gRPC", e.client_uri, t1, len(req.Body), -1, req.Model, err)
 
 	if err != nil {
 		return nil, err
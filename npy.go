@@ -0,0 +1,437 @@
+package embeddings
+
+// Minimal support for reading and writing NumPy .npy (version 1.0) files and .npz archives.
+// https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const npy_magic string = "\x93NUMPY"
+
+// The maximum length, in bytes, of a NumPy header. Longer headers are treated as corrupt rather than allocating arbitrarily large buffers.
+const npy_max_header_len int = 64 << 10
+
+// The maximum length, in characters, of a NumPy unicode ("<U") string.
+const npy_max_string_len int = 1 << 20
+
+var re_npy_descr = regexp.MustCompile(`'descr':\s*'([^']+)'`)
+var re_npy_fortran = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+var re_npy_shape = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+
+// WriteNPY writes 'vector' to 'wr' as a one-dimensional NumPy array.
+func WriteNPY[T Float](wr io.Writer, vector []T) error {
+
+	err := writeNPYHeader(wr, npyFloatDescr[T](), []int{len(vector)})
+
+	if err != nil {
+		return err
+	}
+
+	return EncodeRaw(wr, vector)
+}
+
+// WriteNPYMatrix writes 'vectors', all of which must have the same dimensions, to 'wr' as a two-dimensional NumPy array.
+func WriteNPYMatrix[T Float](wr io.Writer, vectors [][]T) error {
+
+	dims := 0
+
+	if len(vectors) > 0 {
+		dims = len(vectors[0])
+	}
+
+	for idx, v := range vectors {
+
+		if len(v) != dims {
+			return fmt.Errorf("Vector at offset %d has %d dimensions, expected %d", idx, len(v), dims)
+		}
+	}
+
+	err := writeNPYHeader(wr, npyFloatDescr[T](), []int{len(vectors), dims})
+
+	if err != nil {
+		return err
+	}
+
+	for _, v := range vectors {
+
+		err := EncodeRaw(wr, v)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadNPY reads a NumPy array of little-endian float32 ("<f4") or float64 ("<f8") values from 'r' returning
+// its (C-ordered) values, converted to T, and its shape.
+func ReadNPY[T Float](r io.Reader) ([]T, []int, error) {
+
+	descr, shape, err := readNPYHeader(r)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var width int
+
+	switch descr {
+	case "<f4":
+		width = 4
+	case "<f8":
+		width = 8
+	default:
+		return nil, nil, fmt.Errorf("Unsupported NumPy data type '%s'", descr)
+	}
+
+	body, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read NumPy data, %w", err)
+	}
+
+	count := 1
+
+	for _, d := range shape {
+
+		if d != 0 && count > (math.MaxInt/width)/d {
+			return nil, nil, fmt.Errorf("Invalid NumPy shape %v", shape)
+		}
+
+		count *= d
+	}
+
+	if len(body) != count*width {
+		return nil, nil, fmt.Errorf("NumPy data has %d bytes, expected %d", len(body), count*width)
+	}
+
+	vector, err := floatsFromBytes[T](body, width)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return vector, shape, nil
+}
+
+// EncodeNPZ writes 'rsp' to 'wr' as a NumPy .npz archive containing "embeddings", "id", "model" and "precision" arrays.
+func EncodeNPZ[T Float](wr io.Writer, rsp EmbeddingsResponse[T]) error {
+
+	zw := zip.NewWriter(wr)
+
+	add := func(name string, write func(io.Writer) error) error {
+
+		// NumPy's savez stores (rather than compresses) arrays
+
+		fh := &zip.FileHeader{
+			Name:   name + ".npy",
+			Method: zip.Store,
+		}
+
+		fw, err := zw.CreateHeader(fh)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create %s, %w", fh.Name, err)
+		}
+
+		return write(fw)
+	}
+
+	err := add("embeddings", func(fw io.Writer) error {
+		return WriteNPY(fw, rsp.Embeddings())
+	})
+
+	if err != nil {
+		return err
+	}
+
+	strings_map := map[string]string{
+		"id":        rsp.Id(),
+		"model":     rsp.Model(),
+		"precision": rsp.Precision(),
+	}
+
+	for _, k := range []string{"id", "model", "precision"} {
+
+		err := add(k, func(fw io.Writer) error {
+			return writeNPYString(fw, strings_map[k])
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// DecodeNPZ decodes a NumPy .npz archive, of 'size' bytes, written by the `EncodeNPZ` method.
+func DecodeNPZ[T Float](r io.ReaderAt, size int64) (*CommonEmbeddingsResponse[T], error) {
+
+	zr, err := zip.NewReader(r, size)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open archive, %w", err)
+	}
+
+	rsp := &CommonEmbeddingsResponse[T]{}
+
+	for _, f := range zr.File {
+
+		fh, err := f.Open()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open %s, %w", f.Name, err)
+		}
+
+		switch f.Name {
+		case "embeddings.npy":
+			rsp.CommonEmbeddings, _, err = ReadNPY[T](fh)
+		case "id.npy":
+			rsp.CommonId, err = readNPYString(fh)
+		case "model.npy":
+			rsp.CommonModel, err = readNPYString(fh)
+		case "precision.npy":
+			rsp.CommonPrecision, err = readNPYString(fh)
+		}
+
+		fh.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s, %w", f.Name, err)
+		}
+	}
+
+	if rsp.CommonEmbeddings == nil {
+		return nil, fmt.Errorf("Archive is missing embeddings")
+	}
+
+	return rsp, nil
+}
+
+func npyFloatDescr[T Float]() string {
+	return fmt.Sprintf("<f%d", floatWidth[T]())
+}
+
+// writeNPYString writes 's' as a zero-dimensional NumPy unicode ("<U") array.
+func writeNPYString(wr io.Writer, s string) error {
+
+	runes := []rune(s)
+	length := max(1, len(runes))
+
+	err := writeNPYHeader(wr, fmt.Sprintf("<U%d", length), []int{})
+
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, length*4)
+
+	for i, r := range runes {
+		binary.LittleEndian.PutUint32(buf[i*4:], uint32(r))
+	}
+
+	_, err = wr.Write(buf)
+	return err
+}
+
+// readNPYString reads a zero-dimensional NumPy unicode ("<U") array.
+func readNPYString(r io.Reader) (string, error) {
+
+	descr, shape, err := readNPYHeader(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(descr, "<U") || len(shape) != 0 {
+		return "", fmt.Errorf("Unsupported NumPy data type '%s' or shape %v", descr, shape)
+	}
+
+	length, err := strconv.Atoi(strings.TrimPrefix(descr, "<U"))
+
+	if err != nil {
+		return "", fmt.Errorf("Invalid NumPy data type '%s', %w", descr, err)
+	}
+
+	if length < 1 || length > npy_max_string_len {
+		return "", fmt.Errorf("Invalid NumPy data type '%s'", descr)
+	}
+
+	buf := make([]byte, length*4)
+
+	_, err = io.ReadFull(r, buf)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to read NumPy data, %w", err)
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < length; i++ {
+
+		c := rune(binary.LittleEndian.Uint32(buf[i*4:]))
+
+		if c == 0 {
+			break
+		}
+
+		if !utf8.ValidRune(c) {
+			return "", fmt.Errorf("Invalid character at offset %d", i)
+		}
+
+		sb.WriteRune(c)
+	}
+
+	return sb.String(), nil
+}
+
+func writeNPYHeader(wr io.Writer, descr string, shape []int) error {
+
+	dims := make([]string, len(shape))
+
+	for i, d := range shape {
+		dims[i] = strconv.Itoa(d)
+	}
+
+	shape_str := strings.Join(dims, ", ")
+
+	if len(shape) == 1 {
+		shape_str += ","
+	}
+
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shape_str)
+
+	// The magic string (6), version (2), header length (2), header and trailing newline are padded to a multiple of 64 bytes
+
+	prefix_len := len(npy_magic) + 4
+	padding := 64 - ((prefix_len + len(header) + 1) % 64)
+
+	if padding == 64 {
+		padding = 0
+	}
+
+	header = header + strings.Repeat(" ", padding) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npy_magic)
+	buf.Write([]byte{1, 0})
+
+	err := binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString(header)
+
+	_, err = wr.Write(buf.Bytes())
+
+	if err != nil {
+		return fmt.Errorf("Failed to write NumPy header, %w", err)
+	}
+
+	return nil
+}
+
+func readNPYHeader(r io.Reader) (string, []int, error) {
+
+	prefix := make([]byte, len(npy_magic)+2)
+
+	_, err := io.ReadFull(r, prefix)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to read NumPy header, %w", err)
+	}
+
+	if string(prefix[0:len(npy_magic)]) != npy_magic {
+		return "", nil, fmt.Errorf("Invalid NumPy magic string")
+	}
+
+	major := prefix[len(npy_magic)]
+
+	var header_len int
+
+	switch major {
+	case 1:
+
+		var v uint16
+
+		err = binary.Read(r, binary.LittleEndian, &v)
+		header_len = int(v)
+
+	case 2, 3:
+
+		var v uint32
+
+		err = binary.Read(r, binary.LittleEndian, &v)
+		header_len = int(v)
+
+	default:
+		return "", nil, fmt.Errorf("Unsupported NumPy format version %d", major)
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to read NumPy header length, %w", err)
+	}
+
+	if header_len > npy_max_header_len {
+		return "", nil, fmt.Errorf("NumPy header length %d exceeds maximum length %d", header_len, npy_max_header_len)
+	}
+
+	header := make([]byte, header_len)
+
+	_, err = io.ReadFull(r, header)
+
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to read NumPy header, %w", err)
+	}
+
+	descr_m := re_npy_descr.FindSubmatch(header)
+	fortran_m := re_npy_fortran.FindSubmatch(header)
+	shape_m := re_npy_shape.FindSubmatch(header)
+
+	if descr_m == nil || fortran_m == nil || shape_m == nil {
+		return "", nil, fmt.Errorf("Invalid NumPy header")
+	}
+
+	if string(fortran_m[1]) == "True" {
+		return "", nil, fmt.Errorf("Fortran-ordered NumPy arrays are not supported")
+	}
+
+	shape := make([]int, 0)
+
+	for _, d := range strings.Split(string(shape_m[1]), ",") {
+
+		d = strings.TrimSpace(d)
+
+		if d == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(d)
+
+		if err != nil {
+			return "", nil, fmt.Errorf("Invalid NumPy shape, %w", err)
+		}
+
+		if v < 0 {
+			return "", nil, fmt.Errorf("Invalid NumPy shape, negative dimension %d", v)
+		}
+
+		shape = append(shape, v)
+	}
+
+	return string(descr_m[1]), shape, nil
+}
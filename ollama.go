@@ -14,6 +14,7 @@ type OllamaEmbedder[T Float] struct {
 	client    *ollamaClient
 	model     string
 	precision string
+	tasks     *taskOptions
 }
 
 func init() {
@@ -59,6 +60,12 @@ func NewOllamaEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], e
 
 	model := q.Get("model")
 
+	tasks, err := taskOptionsFromQuery(q)
+
+	if err != nil {
+		return nil, err
+	}
+
 	precision := "float32"
 
 	if strings.HasSuffix(u.Scheme, "64") {
@@ -69,6 +76,7 @@ func NewOllamaEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], e
 		client:    cl,
 		model:     model,
 		precision: precision,
+		tasks:     tasks,
 	}
 
 	return e, nil
@@ -76,7 +84,13 @@ func NewOllamaEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], e
 
 func (e *OllamaEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
-	cl_rsp, err := e.client.embeddings(ctx, e.model, string(req.Body))
+	body, err := e.tasks.apply(req, e.model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cl_rsp, err := e.client.embeddings(ctx, e.model, string(body))
 
 	if err != nil {
 		return nil, err
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 type ollamaEmbeddingsRequest struct {
@@ -62,20 +63,15 @@ func (o *ollamaClient) embeddings(ctx context.Context, model string, input strin
 		return nil, err
 	}
 
-	body := bytes.NewReader(enc)
-
-	rsp, err := o.execute(ctx, "/api/embed", body)
+	rsp, err := o.execute(ctx, "/api/embed", model, enc)
 
 	if err != nil {
 		return nil, err
 	}
 
-	defer rsp.Close()
-
 	var emb_rsp *ollamaEmbeddingsResponse
 
-	dec := json.NewDecoder(rsp)
-	err = dec.Decode(&emb_rsp)
+	err = json.Unmarshal(rsp, &emb_rsp)
 
 	if err != nil {
 		return nil, err
@@ -84,7 +80,8 @@ func (o *ollamaClient) embeddings(ctx context.Context, model string, input strin
 	return emb_rsp, nil
 }
 
-func (o *ollamaClient) execute(ctx context.Context, path string, r io.Reader) (io.ReadCloser, error) {
+// execute sends 'body' to 'path' and returns the body of the response.
+func (o *ollamaClient) execute(ctx context.Context, path string, model string, body []byte) ([]byte, error) {
 
 	// http_ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	// defer cancel()
@@ -94,17 +91,36 @@ func (o *ollamaClient) execute(ctx context.Context, path string, r io.Reader) (i
 	u.Host = o.host
 	u.Path = path
 
-	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), r)
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(body))
 
 	if err != nil {
 		return nil, err
 	}
 
+	injectTraceHeaders(ctx, req.Header)
+
+	t1 := time.Now()
+
 	rsp, err := o.client.Do(req)
 
 	if err != nil {
+		logHTTPRequest(ctx, req, 0, t1, len(body), -1, model, err)
 		return nil, err
 	}
 
-	return rsp.Body, nil
+	defer rsp.Body.Close()
+
+	rsp_body, err := io.ReadAll(rsp.Body)
+
+	logHTTPRequest(ctx, req, rsp.StatusCode, t1, len(body), len(rsp_body), model, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: rsp.StatusCode, Status: rsp.Status}
+	}
+
+	return rsp_body, nil
 }
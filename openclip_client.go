@@ -14,6 +14,7 @@ import (
 type OpenCLIPEmbedder[T Float] struct {
 	Embedder[T]
 	client    *LocalClient
+	server    *LocalServer
 	precision string
 }
 
@@ -34,13 +35,7 @@ func NewOpenCLIPEmbedder[T Float](ctx context.Context, uri string) (Embedder[T],
 
 	q := u.Query()
 
-	client_uri := "http://localhost:5000"
-
-	if q.Has("server-uri") {
-		client_uri = q.Get("server-uri")
-	}
-
-	local_cl, err := NewLocalClient(ctx, client_uri)
+	local_cl, server, err := newLocalClientFromQuery(ctx, q, "openclip_server.py")
 
 	if err != nil {
 		return nil, err
@@ -48,11 +43,22 @@ func NewOpenCLIPEmbedder[T Float](ctx context.Context, uri string) (Embedder[T],
 
 	e := &OpenCLIPEmbedder[T]{
 		client: local_cl,
+		server: server,
 	}
 
 	return e, nil
 }
 
+// Close stops the local server launched by 'e', if present.
+func (e *OpenCLIPEmbedder[T]) Close(ctx context.Context) error {
+
+	if e.server == nil {
+		return nil
+	}
+
+	return e.server.Close(ctx)
+}
+
 func (e *OpenCLIPEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
 	cl_req := &LocalClientEmbeddingRequest{
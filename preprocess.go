@@ -0,0 +1,248 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+)
+
+// Image input sizes (and resize modes) for common model families. Presets always enlarge smaller images since
+// models expect inputs of a fixed size.
+var preprocess_presets = map[string]*ImagePreprocessOptions{
+	"clip":        {Width: 224, Height: 224, Mode: RESIZE_CROP, Upscale: true},
+	"clip-336":    {Width: 336, Height: 336, Mode: RESIZE_CROP, Upscale: true},
+	"mobileclip":  {Width: 256, Height: 256, Mode: RESIZE_CROP, Upscale: true},
+	"openclip":    {Width: 224, Height: 224, Mode: RESIZE_CROP, Upscale: true},
+	"siglip-224":  {Width: 224, Height: 224, Mode: RESIZE_CROP, Upscale: true},
+	"siglip-384":  {Width: 384, Height: 384, Mode: RESIZE_CROP, Upscale: true},
+	"siglip-512":  {Width: 512, Height: 512, Mode: RESIZE_CROP, Upscale: true},
+	"siglip2-512": {Width: 512, Height: 512, Mode: RESIZE_FIT, Upscale: true},
+}
+
+// ImagePreprocessOptions defines the options used to preprocess images.
+type ImagePreprocessOptions struct {
+	// The target width of the image.
+	Width int
+	// The target height of the image.
+	Height int
+	// The resize mode. Valid options are "fit" and "crop".
+	Mode string
+	// Enlarge images smaller than the target dimensions.
+	Upscale bool
+	// The format to encode the final image as. Valid options are "jpeg" and "png".
+	Format string
+	// The quality of JPEG-encoded images.
+	Quality int
+	// The maximum number of pixels in source images. Larger images are rejected before they are decoded. A value of 0 means there is no limit.
+	MaxPixels int
+}
+
+// PreprocessEmbedder implements the `Embedder` interface by decoding, orienting, resizing and re-encoding
+// images before passing them to another `Embedder` instance.
+type PreprocessEmbedder[T Float] struct {
+	Embedder[T]
+	embedder Embedder[T]
+	options  *ImagePreprocessOptions
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "preprocess", NewPreprocessEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "preprocess32", NewPreprocessEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "preprocess64", NewPreprocessEmbedder[float64])
+}
+
+// NewPreprocessEmbedder creates a new `PreprocessEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	preprocess://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `preset` – The name of a model-specific preset defining size and resize mode.
+// * `size` – The target width and height of images. Default is 224.
+// * `width` – The target width of images. Overrides `size`.
+// * `height` – The target height of images. Overrides `size`.
+// * `mode` – The resize mode. Valid options are "fit" and "crop". Default is "fit".
+// * `upscale` – Enlarge images smaller than the target dimensions. Default is false.
+// * `format` – The format to encode images as. Valid options are "jpeg" and "png". Default is "jpeg".
+// * `quality` – The quality of JPEG-encoded images. Default is 90.
+// * `max-pixels` – The maximum number of pixels (width x height) in source images. Default is `DEFAULT_IMAGE_MAX_PIXELS`.
+func NewPreprocessEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	opts, err := imagePreprocessOptionsFromQuery(q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &PreprocessEmbedder[T]{
+		embedder: emb,
+		options:  opts,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings passes 'req' to the underlying embedder without modification.
+func (e *PreprocessEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embedder.TextEmbeddings(ctx, req)
+}
+
+// ImageEmbeddings preprocesses the body of 'req' and passes the result to the underlying embedder.
+func (e *PreprocessEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	body, err := PreprocessImage(req.Body, e.options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Preprocessed image", "id", req.Id, "source_size", len(req.Body), "size", len(body))
+
+	pre_req := &EmbeddingsRequest{
+		Id:    req.Id,
+		Model: req.Model,
+		Body:  body,
+		Task:  req.Task,
+	}
+
+	return e.embedder.ImageEmbeddings(ctx, pre_req)
+}
+
+// Close closes the underlying embedder.
+func (e *PreprocessEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+// PreprocessImage decodes 'body', applies any EXIF orientation, converts it to 8-bit RGB, resizes it according to 'opts'
+// and returns the re-encoded result.
+func PreprocessImage(body []byte, opts *ImagePreprocessOptions) ([]byte, error) {
+
+	im, _, err := DecodeImage(body, opts.MaxPixels)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resized, err := ResizeImage(im, opts.Width, opts.Height, opts.Mode, opts.Upscale)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resize image, %w", err)
+	}
+
+	return EncodeImage(resized, opts.Format, opts.Quality)
+}
+
+func imagePreprocessOptionsFromQuery(q url.Values) (*ImagePreprocessOptions, error) {
+
+	opts := &ImagePreprocessOptions{
+		Width:     224,
+		Height:    224,
+		Mode:      RESIZE_FIT,
+		Format:    "jpeg",
+		Quality:   90,
+		MaxPixels: DEFAULT_IMAGE_MAX_PIXELS,
+	}
+
+	if q.Has("preset") {
+
+		preset, exists := preprocess_presets[q.Get("preset")]
+
+		if !exists {
+			return nil, fmt.Errorf("Invalid or unsupported ?preset= parameter")
+		}
+
+		opts.Width = preset.Width
+		opts.Height = preset.Height
+		opts.Mode = preset.Mode
+		opts.Upscale = preset.Upscale
+	}
+
+	int_params := map[string][]*int{
+		"size":       {&opts.Width, &opts.Height},
+		"width":      {&opts.Width},
+		"height":     {&opts.Height},
+		"quality":    {&opts.Quality},
+		"max-pixels": {&opts.MaxPixels},
+	}
+
+	// Process "size" before "width" and "height" so that they can override it
+
+	for _, k := range []string{"size", "width", "height", "quality", "max-pixels"} {
+
+		if !q.Has(k) {
+			continue
+		}
+
+		v, err := strconv.Atoi(q.Get(k))
+
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("Invalid ?%s= parameter", k)
+		}
+
+		for _, ptr := range int_params[k] {
+			*ptr = v
+		}
+	}
+
+	if q.Has("mode") {
+
+		opts.Mode = q.Get("mode")
+
+		switch opts.Mode {
+		case RESIZE_FIT, RESIZE_CROP:
+			// pass
+		default:
+			return nil, fmt.Errorf("Invalid ?mode= parameter")
+		}
+	}
+
+	if q.Has("upscale") {
+
+		v, err := strconv.ParseBool(q.Get("upscale"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?upscale= parameter, %w", err)
+		}
+
+		opts.Upscale = v
+	}
+
+	if q.Has("format") {
+
+		opts.Format = q.Get("format")
+
+		switch opts.Format {
+		case "jpeg", "png":
+			// pass
+		default:
+			return nil, fmt.Errorf("Invalid ?format= parameter")
+		}
+	}
+
+	return opts, nil
+}
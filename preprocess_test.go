@@ -0,0 +1,167 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestPreprocessImageEmbeddings(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "preprocess://?client-uri=testing://&preset=clip&format=png")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	im_path := "fixtures/1527845303_walrus.jpg"
+
+	im_body, err := os.ReadFile(im_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read data from %s, %v", im_path, err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: im_body,
+	}
+
+	rsp, err := emb.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	// The testing embedder returns the size of the request body as the first element
+
+	if rsp.Embeddings()[0] == float32(len(im_body)) {
+		t.Fatalf("Expected image to be preprocessed")
+	}
+
+	opts := &ImagePreprocessOptions{
+		Width:   224,
+		Height:  224,
+		Mode:    RESIZE_CROP,
+		Upscale: true,
+		Format:  "jpeg",
+		Quality: 90,
+	}
+
+	body, err := PreprocessImage(im_body, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to preprocess image, %v", err)
+	}
+
+	im, _, err := DecodeImage(body, DEFAULT_IMAGE_MAX_PIXELS)
+
+	if err != nil {
+		t.Fatalf("Failed to decode preprocessed image, %v", err)
+	}
+
+	if im.Bounds().Dx() != 224 || im.Bounds().Dy() != 224 {
+		t.Fatalf("Unexpected dimensions for preprocessed image: %v", im.Bounds())
+	}
+}
+
+func TestPreprocessEmbedderInvalidParameters(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	_, err := NewEmbedder32(ctx, "preprocess://?client-uri=testing://&mode=stretch")
+
+	if err == nil {
+		t.Fatalf("Expected invalid ?mode= parameter to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected underlying embedder not to be left open")
+	}
+}
+
+func TestDecodeImageOrientation(t *testing.T) {
+
+	im := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+
+	body, err := EncodeImage(im, "jpeg", 90)
+
+	if err != nil {
+		t.Fatalf("Failed to encode image, %v", err)
+	}
+
+	// Insert an APP1 (EXIF) segment with orientation 6 (rotate 90 degrees clockwise) after the SOI marker
+
+	tiff := []byte("MM\x00*\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:2], exif_orientation_tag)
+	binary.BigEndian.PutUint16(entry[2:4], 3)
+	binary.BigEndian.PutUint32(entry[4:8], 1)
+	binary.BigEndian.PutUint16(entry[8:10], 6)
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0, 0, 0, 0)
+
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:4], uint16(len(exif)+2))
+	app1 = append(app1, exif...)
+
+	oriented := append([]byte{}, body[0:2]...)
+	oriented = append(oriented, app1...)
+	oriented = append(oriented, body[2:]...)
+
+	decoded, _, err := DecodeImage(oriented, DEFAULT_IMAGE_MAX_PIXELS)
+
+	if err != nil {
+		t.Fatalf("Failed to decode image, %v", err)
+	}
+
+	if decoded.Bounds().Dx() != 10 || decoded.Bounds().Dy() != 20 {
+		t.Fatalf("Expected image to be rotated, %v", decoded.Bounds())
+	}
+}
+
+func TestDecodeImageMaxPixels(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 100, 50)))
+
+	if err != nil {
+		t.Fatalf("Failed to encode image, %v", err)
+	}
+
+	_, _, err = DecodeImage(buf.Bytes(), 5000)
+
+	if err != nil {
+		t.Fatalf("Failed to decode image, %v", err)
+	}
+
+	_, _, err = DecodeImage(buf.Bytes(), 4999)
+
+	if err == nil {
+		t.Fatalf("Expected image exceeding maximum pixels to fail")
+	}
+
+	opts := &ImagePreprocessOptions{
+		Width:     10,
+		Height:    10,
+		Mode:      RESIZE_FIT,
+		Format:    "png",
+		MaxPixels: 100,
+	}
+
+	_, err = PreprocessImage(buf.Bytes(), opts)
+
+	if err == nil {
+		t.Fatalf("Expected preprocessing image exceeding maximum pixels to fail")
+	}
+}
@@ -0,0 +1,206 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sfomuseum/go-embeddings/projection"
+)
+
+// ProjectedEmbeddingsResponse is an `EmbeddingsResponse` implementation which records the original
+// dimensions of embeddings that have been projected.
+type ProjectedEmbeddingsResponse[T Float] struct {
+	CommonEmbeddingsResponse[T]
+	OriginalDimensions  int `json:"original_dimensions"`
+	ProjectedDimensions int `json:"projected_dimensions"`
+}
+
+// ProjectEmbedder implements the `Embedder` interface by applying a linear projection (for example a PCA
+// projection derived using the `projection` package) to the embeddings derived by another `Embedder` instance.
+type ProjectEmbedder[T Float] struct {
+	Embedder[T]
+	embedder   Embedder[T]
+	projection *projection.Projection
+	normalize  bool
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "project", NewProjectEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "project32", NewProjectEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "project64", NewProjectEmbedder[float64])
+}
+
+// NewProjectEmbedder creates a new `ProjectEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	project://?client-uri={CLIENT_URI}&path={PATH}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `path` – The path to a JSON-encoded `projection.Projection` or a NumPy (.npy) file containing a matrix with one row for each input
+// dimension and one column for each output dimension (such that projected = embeddings @ matrix). Required.
+// * `normalize` – A boolean flag indicating whether projected embeddings should be L2-normalized. Default is false.
+func NewProjectEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	if !q.Has("path") {
+		return nil, fmt.Errorf("Missing ?path= parameter")
+	}
+
+	p, err := ReadProjection(q.Get("path"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	normalize := false
+
+	if q.Has("normalize") {
+
+		v, err := strconv.ParseBool(q.Get("normalize"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?normalize= parameter, %w", err)
+		}
+
+		normalize = v
+	}
+
+	client_uri := q.Get("client-uri")
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &ProjectEmbedder[T]{
+		embedder:   emb,
+		projection: p,
+		normalize:  normalize,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives embeddings for 'req' using the underlying embedder and returns a `ProjectedEmbeddingsResponse` instance.
+func (e *ProjectEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	rsp, err := e.embedder.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.project(rsp)
+}
+
+// ImageEmbeddings derives embeddings for 'req' using the underlying embedder and returns a `ProjectedEmbeddingsResponse` instance.
+func (e *ProjectEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	rsp, err := e.embedder.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.project(rsp)
+}
+
+// Close closes the underlying embedder.
+func (e *ProjectEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *ProjectEmbedder[T]) project(rsp EmbeddingsResponse[T]) (EmbeddingsResponse[T], error) {
+
+	projected, err := projection.Apply(e.projection, rsp.Embeddings())
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to project embeddings, %w", err)
+	}
+
+	if e.normalize {
+		projected = Normalize(projected)
+	}
+
+	p_rsp := &ProjectedEmbeddingsResponse[T]{
+		CommonEmbeddingsResponse: CommonEmbeddingsResponse[T]{
+			CommonId:         rsp.Id(),
+			CommonEmbeddings: projected,
+			CommonModel:      rsp.Model(),
+			CommonCreated:    rsp.Created(),
+			CommonPrecision:  rsp.Precision(),
+		},
+		OriginalDimensions:  len(rsp.Embeddings()),
+		ProjectedDimensions: len(projected),
+	}
+
+	return p_rsp, nil
+}
+
+// ReadProjection reads a projection from 'path'. Files with a ".npy" extension are read as NumPy matrices with one row for
+// each input dimension and one column for each output dimension (such that projected = embeddings @ matrix); all other files
+// are read as JSON-encoded `projection.Projection` instances.
+func ReadProjection(path string) (*projection.Projection, error) {
+
+	r, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open projection, %w", err)
+	}
+
+	defer r.Close()
+
+	if strings.ToLower(filepath.Ext(path)) != ".npy" {
+		return projection.Read(r)
+	}
+
+	values, shape, err := ReadNPY[float64](r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read projection matrix, %w", err)
+	}
+
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("Projection matrix must have 2 dimensions, got %d", len(shape))
+	}
+
+	in_dims := shape[0]
+	out_dims := shape[1]
+
+	if in_dims < 1 || out_dims < 1 {
+		return nil, fmt.Errorf("Invalid projection matrix shape %v", shape)
+	}
+
+	matrix := make([][]float64, out_dims)
+
+	for j := range matrix {
+
+		matrix[j] = make([]float64, in_dims)
+
+		for i := range matrix[j] {
+			matrix[j][i] = values[(i*out_dims)+j]
+		}
+	}
+
+	return projection.NewLinearProjection(matrix, nil)
+}
@@ -0,0 +1,149 @@
+package embeddings
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sfomuseum/go-embeddings/projection"
+)
+
+func TestProjectEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	// testing:// embeddings with 4 dimensions are [ len(body), 1, 2, 3 ]
+
+	p, err := projection.NewLinearProjection([][]float64{{1, 0, 0, 0}, {0, 1, 1, 1}}, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to create projection, %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "projection.json")
+
+	wr, err := os.Create(path)
+
+	if err != nil {
+		t.Fatalf("Failed to create projection file, %v", err)
+	}
+
+	err = p.Write(wr)
+
+	if err != nil {
+		t.Fatalf("Failed to write projection, %v", err)
+	}
+
+	wr.Close()
+
+	emb, err := NewEmbedder32(ctx, "project://?client-uri=testing://&path="+url.QueryEscape(path))
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	rsp, err := emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if len(rsp.Embeddings()) != 2 || rsp.Embeddings()[0] != 11 || rsp.Embeddings()[1] != 6 {
+		t.Fatalf("Unexpected embeddings, %v", rsp.Embeddings())
+	}
+
+	p_rsp := rsp.(*ProjectedEmbeddingsResponse[float32])
+
+	if p_rsp.OriginalDimensions != 4 || p_rsp.ProjectedDimensions != 2 {
+		t.Fatalf("Unexpected dimensions, %d, %d", p_rsp.OriginalDimensions, p_rsp.ProjectedDimensions)
+	}
+
+	// Embeddings with the wrong dimensions fail
+
+	emb, err = NewEmbedder32(ctx, "project://?client-uri=testing://%3Fdimensions=8&path="+url.QueryEscape(path))
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	_, err = emb.ImageEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected mismatched dimensions to fail")
+	}
+}
+
+func TestReadProjectionNPY(t *testing.T) {
+
+	// A (4 x 2) matrix, applied as embeddings @ matrix
+
+	matrix := [][]float32{
+		{1, 0},
+		{0, 1},
+		{0, 1},
+		{0, 1},
+	}
+
+	path := filepath.Join(t.TempDir(), "projection.npy")
+
+	wr, err := os.Create(path)
+
+	if err != nil {
+		t.Fatalf("Failed to create projection file, %v", err)
+	}
+
+	err = WriteNPYMatrix(wr, matrix)
+
+	if err != nil {
+		t.Fatalf("Failed to write matrix, %v", err)
+	}
+
+	wr.Close()
+
+	p, err := ReadProjection(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read projection, %v", err)
+	}
+
+	projected, err := projection.Apply(p, []float32{11, 1, 2, 3})
+
+	if err != nil {
+		t.Fatalf("Failed to apply projection, %v", err)
+	}
+
+	if projected[0] != 11 || projected[1] != 6 {
+		t.Fatalf("Unexpected projection, %v", projected)
+	}
+}
+
+func TestReadProjectionNPYInvalidShape(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "projection.npy")
+
+	wr, err := os.Create(path)
+
+	if err != nil {
+		t.Fatalf("Failed to create projection file, %v", err)
+	}
+
+	err = writeNPYHeader(wr, "<f8", []int{0, 4})
+
+	if err != nil {
+		t.Fatalf("Failed to write header, %v", err)
+	}
+
+	wr.Close()
+
+	_, err = ReadProjection(path)
+
+	if err == nil {
+		t.Fatalf("Expected projection with no input dimensions to fail")
+	}
+}
@@ -0,0 +1,311 @@
+package projection
+
+import (
+	"math"
+)
+
+// symmetricEigen returns the eigenvalues, in ascending order, and the corresponding eigenvectors (as the rows of the
+// returned matrix) of the symmetric matrix 'a'. It uses Householder tridiagonalization followed by the implicit QL algorithm,
+// adapted from the public domain JAMA library (which in turn derives from the EISPACK routines tred2 and tql2). 'a' is not modified.
+func symmetricEigen(a [][]float64) ([]float64, [][]float64) {
+
+	n := len(a)
+
+	v := make([][]float64, n)
+
+	for i := range v {
+		v[i] = make([]float64, n)
+		copy(v[i], a[i])
+	}
+
+	d := make([]float64, n)
+	e := make([]float64, n)
+
+	tred2(v, d, e)
+
+	// tql2 operates on the transpose of 'v', so that eigenvectors are stored in (contiguous) rows
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			v[i][j], v[j][i] = v[j][i], v[i][j]
+		}
+	}
+
+	tql2(v, d, e)
+
+	return d, v
+}
+
+// tred2 reduces the symmetric matrix 'v' to tridiagonal form, storing the diagonal in 'd', the subdiagonal in 'e' and the
+// accumulated orthogonal transformations in 'v'.
+func tred2(v [][]float64, d []float64, e []float64) {
+
+	n := len(v)
+
+	for j := 0; j < n; j++ {
+		d[j] = v[n-1][j]
+	}
+
+	for i := n - 1; i > 0; i-- {
+
+		scale := 0.0
+		h := 0.0
+
+		for k := 0; k < i; k++ {
+			scale += math.Abs(d[k])
+		}
+
+		if scale == 0.0 {
+
+			e[i] = d[i-1]
+
+			for j := 0; j < i; j++ {
+				d[j] = v[i-1][j]
+				v[i][j] = 0.0
+				v[j][i] = 0.0
+			}
+
+		} else {
+
+			for k := 0; k < i; k++ {
+				d[k] /= scale
+				h += d[k] * d[k]
+			}
+
+			f := d[i-1]
+			g := math.Sqrt(h)
+
+			if f > 0 {
+				g = -g
+			}
+
+			e[i] = scale * g
+			h = h - f*g
+			d[i-1] = f - g
+
+			for j := 0; j < i; j++ {
+				e[j] = 0.0
+			}
+
+			for j := 0; j < i; j++ {
+
+				f = d[j]
+				v[j][i] = f
+				g = e[j] + v[j][j]*f
+
+				for k := j + 1; k <= i-1; k++ {
+					g += v[k][j] * d[k]
+					e[k] += v[k][j] * f
+				}
+
+				e[j] = g
+			}
+
+			f = 0.0
+
+			for j := 0; j < i; j++ {
+				e[j] /= h
+				f += e[j] * d[j]
+			}
+
+			hh := f / (h + h)
+
+			for j := 0; j < i; j++ {
+				e[j] -= hh * d[j]
+			}
+
+			for j := 0; j < i; j++ {
+
+				f = d[j]
+				g = e[j]
+
+				for k := j; k <= i-1; k++ {
+					v[k][j] -= (f*e[k] + g*d[k])
+				}
+
+				d[j] = v[i-1][j]
+				v[i][j] = 0.0
+			}
+		}
+
+		d[i] = h
+	}
+
+	// Accumulate transformations
+
+	for i := 0; i < n-1; i++ {
+
+		v[n-1][i] = v[i][i]
+		v[i][i] = 1.0
+		h := d[i+1]
+
+		if h != 0.0 {
+
+			for k := 0; k <= i; k++ {
+				d[k] = v[k][i+1] / h
+			}
+
+			for j := 0; j <= i; j++ {
+
+				g := 0.0
+
+				for k := 0; k <= i; k++ {
+					g += v[k][i+1] * v[k][j]
+				}
+
+				for k := 0; k <= i; k++ {
+					v[k][j] -= g * d[k]
+				}
+			}
+		}
+
+		for k := 0; k <= i; k++ {
+			v[k][i+1] = 0.0
+		}
+	}
+
+	for j := 0; j < n; j++ {
+		d[j] = v[n-1][j]
+		v[n-1][j] = 0.0
+	}
+
+	v[n-1][n-1] = 1.0
+	e[0] = 0.0
+}
+
+// tql2 computes the eigenvalues ('d') and eigenvectors (the rows of 'v', which must be the transpose of the matrix produced by tred2)
+// of the tridiagonal matrix produced by tred2 and sorts them in ascending order.
+func tql2(v [][]float64, d []float64, e []float64) {
+
+	n := len(v)
+
+	for i := 1; i < n; i++ {
+		e[i-1] = e[i]
+	}
+
+	e[n-1] = 0.0
+
+	f := 0.0
+	tst1 := 0.0
+	eps := math.Pow(2.0, -52.0)
+
+	for l := 0; l < n; l++ {
+
+		// Find small subdiagonal element
+
+		tst1 = max(tst1, math.Abs(d[l])+math.Abs(e[l]))
+
+		m := l
+
+		for m < n-1 {
+
+			if math.Abs(e[m]) <= eps*tst1 {
+				break
+			}
+
+			m++
+		}
+
+		// If m == l, d[l] is an eigenvalue, otherwise iterate
+
+		if m > l {
+
+			for {
+
+				// Compute implicit shift
+
+				g := d[l]
+				p := (d[l+1] - g) / (2.0 * e[l])
+				r := math.Hypot(p, 1.0)
+
+				if p < 0 {
+					r = -r
+				}
+
+				d[l] = e[l] / (p + r)
+				d[l+1] = e[l] * (p + r)
+				dl1 := d[l+1]
+				h := g - d[l]
+
+				for i := l + 2; i < n; i++ {
+					d[i] -= h
+				}
+
+				f += h
+
+				// Implicit QL transformation
+
+				p = d[m]
+				c := 1.0
+				c2 := c
+				c3 := c
+				el1 := e[l+1]
+				s := 0.0
+				s2 := 0.0
+
+				for i := m - 1; i >= l; i-- {
+
+					c3 = c2
+					c2 = c
+					s2 = s
+					g = c * e[i]
+					h = c * p
+					r = math.Hypot(p, e[i])
+					e[i+1] = s * r
+					s = e[i] / r
+					c = p / r
+					p = c*d[i] - s*g
+					d[i+1] = h + s*(c*g+s*d[i])
+
+					// Accumulate transformation
+
+					vi := v[i]
+					vi1 := v[i+1]
+
+					for k := 0; k < n; k++ {
+						h = vi1[k]
+						vi1[k] = s*vi[k] + c*h
+						vi[k] = c*vi[k] - s*h
+					}
+				}
+
+				p = -s * s2 * c3 * el1 * e[l] / dl1
+				e[l] = s * p
+				d[l] = c * p
+
+				// Check for convergence
+
+				if math.Abs(e[l]) <= eps*tst1 {
+					break
+				}
+			}
+		}
+
+		d[l] = d[l] + f
+		e[l] = 0.0
+	}
+
+	// Sort eigenvalues and corresponding vectors
+
+	for i := 0; i < n-1; i++ {
+
+		k := i
+		p := d[i]
+
+		for j := i + 1; j < n; j++ {
+
+			if d[j] < p {
+				k = j
+				p = d[j]
+			}
+		}
+
+		if k != i {
+
+			d[k] = d[i]
+			d[i] = p
+
+			v[i], v[k] = v[k], v[i]
+		}
+	}
+}
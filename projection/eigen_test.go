@@ -0,0 +1,45 @@
+package projection
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSymmetricEigen(t *testing.T) {
+
+	a := [][]float64{
+		{4, 1, 2},
+		{1, 3, 0},
+		{2, 0, 5},
+	}
+
+	values, vectors := symmetricEigen(a)
+
+	for k := range values {
+
+		if k > 0 && values[k] < values[k-1] {
+			t.Fatalf("Eigenvalues are not sorted, %v", values)
+		}
+
+		// A·v = λ·v
+
+		for i := range a {
+
+			av := 0.0
+
+			for j := range a {
+				av += a[i][j] * vectors[k][j]
+			}
+
+			if math.Abs(av-values[k]*vectors[k][i]) > 1e-9 {
+				t.Fatalf("Eigenvector %d is incorrect", k)
+			}
+		}
+	}
+
+	// The trace equals the sum of the eigenvalues
+
+	if math.Abs(values[0]+values[1]+values[2]-12) > 1e-9 {
+		t.Fatalf("Unexpected eigenvalues, %v", values)
+	}
+}
@@ -0,0 +1,99 @@
+package projection
+
+import (
+	"fmt"
+	"math"
+)
+
+// PCAOptions defines options for fitting PCA projections.
+type PCAOptions struct {
+	// Scale each component by the inverse of its standard deviation so that the projected embeddings have unit variance.
+	Whiten bool
+}
+
+// FitPCA returns a `Projection` which maps embeddings on to the first 'components' principal components of 'samples'.
+// The principal components are the eigenvectors of the covariance matrix of 'samples' with the largest eigenvalues. 'opts'
+// may be nil in which case default options are used.
+func FitPCA[T Float](samples [][]T, components int, opts *PCAOptions) (*Projection, error) {
+
+	if opts == nil {
+		opts = &PCAOptions{}
+	}
+
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("At least 2 samples are required")
+	}
+
+	m, err := mean(samples)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dims := len(m)
+
+	if components < 1 || components > dims {
+		return nil, fmt.Errorf("Invalid number of components, must be between 1 and %d", dims)
+	}
+
+	x := centered(samples, m)
+	cov := gram(x, x)
+
+	for i := range cov {
+		for j := range cov[i] {
+			cov[i][j] /= float64(len(samples) - 1)
+		}
+	}
+
+	// Eigenvalues are returned in ascending order
+
+	values, vectors := symmetricEigen(cov)
+
+	p := &Projection{
+		Method:            METHOD_PCA,
+		InputDimensions:   dims,
+		OutputDimensions:  components,
+		Mean:              m,
+		Matrix:            make([][]float64, components),
+		ExplainedVariance: make([]float64, components),
+	}
+
+	for k := 0; k < components; k++ {
+
+		col := dims - 1 - k
+		variance := max(0, values[col])
+
+		row := make([]float64, dims)
+		largest := 0
+
+		for i := range row {
+
+			row[i] = vectors[col][i]
+
+			if math.Abs(row[i]) > math.Abs(row[largest]) {
+				largest = i
+			}
+		}
+
+		// Make the sign of each component deterministic
+
+		scale := 1.0
+
+		if row[largest] < 0 {
+			scale = -1.0
+		}
+
+		if opts.Whiten && variance > 0 {
+			scale = scale / math.Sqrt(variance)
+		}
+
+		for i := range row {
+			row[i] *= scale
+		}
+
+		p.Matrix[k] = row
+		p.ExplainedVariance[k] = variance
+	}
+
+	return p, nil
+}
@@ -0,0 +1,94 @@
+package projection
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestFitPCA(t *testing.T) {
+
+	r := rand.New(rand.NewPCG(3, 4))
+
+	// Samples vary mostly along (1, 1, 0), less along (1, -1, 0) and hardly at all along (0, 0, 1)
+
+	samples := make([][]float32, 500)
+
+	for i := range samples {
+
+		a := r.NormFloat64() * 10
+		b := r.NormFloat64() * 2
+		c := r.NormFloat64() * 0.1
+
+		samples[i] = []float32{
+			float32(5 + (a+b)/math.Sqrt2),
+			float32(-3 + (a-b)/math.Sqrt2),
+			float32(1 + c),
+		}
+	}
+
+	p, err := FitPCA(samples, 2, nil)
+
+	if err != nil {
+		t.Fatalf("Failed to fit PCA, %v", err)
+	}
+
+	if p.InputDimensions != 3 || p.OutputDimensions != 2 || p.Method != METHOD_PCA {
+		t.Fatalf("Unexpected projection, %+v", p)
+	}
+
+	expected := [][]float64{
+		{math.Sqrt2 / 2, math.Sqrt2 / 2, 0},
+		{math.Sqrt2 / 2, -math.Sqrt2 / 2, 0},
+	}
+
+	for k, row := range expected {
+
+		if math.Abs(math.Abs(dot(row, p.Matrix[k]))-1) > 1e-3 {
+			t.Fatalf("Unexpected component %d, %v", k, p.Matrix[k])
+		}
+	}
+
+	if p.ExplainedVariance[0] < p.ExplainedVariance[1] {
+		t.Fatalf("Components are not ordered by explained variance, %v", p.ExplainedVariance)
+	}
+
+	if math.Abs(p.ExplainedVariance[0]-100)/100 > 0.2 {
+		t.Fatalf("Unexpected explained variance, %v", p.ExplainedVariance)
+	}
+
+	projected, err := Apply(p, samples[0])
+
+	if err != nil {
+		t.Fatalf("Failed to apply projection, %v", err)
+	}
+
+	if len(projected) != 2 {
+		t.Fatalf("Expected 2 dimensions, got %d", len(projected))
+	}
+
+	// Whitened components have unit variance
+
+	p, err = FitPCA(samples, 1, &PCAOptions{Whiten: true})
+
+	if err != nil {
+		t.Fatalf("Failed to fit PCA, %v", err)
+	}
+
+	sum := 0.0
+
+	for _, s := range samples {
+		v, _ := Apply(p, s)
+		sum += float64(v[0]) * float64(v[0])
+	}
+
+	if math.Abs(sum/float64(len(samples)-1)-1) > 0.01 {
+		t.Fatalf("Whitened component does not have unit variance, %f", sum/float64(len(samples)-1))
+	}
+
+	_, err = FitPCA(samples, 4, nil)
+
+	if err == nil {
+		t.Fatalf("Expected too many components to fail")
+	}
+}
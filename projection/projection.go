@@ -0,0 +1,269 @@
+// Package projection provides methods for fitting, persisting and applying linear projections to embeddings,
+// for example to reduce their dimensions using principal component analysis (PCA) or to align embeddings derived
+// by one model with the embeddings derived by another. It has no dependencies outside the standard library.
+package projection
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// A projection derived using principal component analysis.
+	METHOD_PCA string = "pca"
+	// A projection derived by fitting a linear (ridge) regression between two sets of embeddings.
+	METHOD_REGRESSION string = "regression"
+	// An arbitrary linear projection.
+	METHOD_LINEAR string = "linear"
+)
+
+// Float is a type constraint for the floating point types that projections can be applied to.
+type Float interface {
+	~float32 | ~float64
+}
+
+// Projection defines a linear projection, y = Matrix · (x - Mean) + Bias, from embeddings with `InputDimensions`
+// dimensions to embeddings with `OutputDimensions` dimensions.
+type Projection struct {
+	// The method used to derive the projection.
+	Method string `json:"method"`
+	// The number of dimensions of the embeddings the projection is applied to.
+	InputDimensions int `json:"input_dimensions"`
+	// The number of dimensions of the projected embeddings.
+	OutputDimensions int `json:"output_dimensions"`
+	// An optional vector, with `InputDimensions` elements, subtracted from embeddings before they are projected.
+	Mean []float64 `json:"mean,omitempty"`
+	// The projection matrix with `OutputDimensions` rows and `InputDimensions` columns.
+	Matrix [][]float64 `json:"matrix"`
+	// An optional vector, with `OutputDimensions` elements, added to projected embeddings.
+	Bias []float64 `json:"bias,omitempty"`
+	// For PCA projections, the variance explained by each component.
+	ExplainedVariance []float64 `json:"explained_variance,omitempty"`
+}
+
+// NewLinearProjection returns a new `Projection` for 'matrix', which must have one row for each output dimension and
+// one column for each input dimension, and an optional 'bias' vector.
+func NewLinearProjection(matrix [][]float64, bias []float64) (*Projection, error) {
+
+	if len(matrix) == 0 {
+		return nil, fmt.Errorf("Empty matrix")
+	}
+
+	p := &Projection{
+		Method:           METHOD_LINEAR,
+		InputDimensions:  len(matrix[0]),
+		OutputDimensions: len(matrix),
+		Matrix:           matrix,
+		Bias:             bias,
+	}
+
+	err := p.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Validate returns an error if the dimensions of the projection's properties are inconsistent.
+func (p *Projection) Validate() error {
+
+	if p.InputDimensions < 1 || p.OutputDimensions < 1 {
+		return fmt.Errorf("Invalid dimensions")
+	}
+
+	if len(p.Matrix) != p.OutputDimensions {
+		return fmt.Errorf("Matrix has %d rows, expected %d", len(p.Matrix), p.OutputDimensions)
+	}
+
+	for idx, row := range p.Matrix {
+
+		if len(row) != p.InputDimensions {
+			return fmt.Errorf("Matrix row %d has %d columns, expected %d", idx, len(row), p.InputDimensions)
+		}
+	}
+
+	if p.Mean != nil && len(p.Mean) != p.InputDimensions {
+		return fmt.Errorf("Mean has %d dimensions, expected %d", len(p.Mean), p.InputDimensions)
+	}
+
+	if p.Bias != nil && len(p.Bias) != p.OutputDimensions {
+		return fmt.Errorf("Bias has %d dimensions, expected %d", len(p.Bias), p.OutputDimensions)
+	}
+
+	return nil
+}
+
+// Write encodes the projection as JSON and writes it to 'wr'.
+func (p *Projection) Write(wr io.Writer) error {
+
+	enc := json.NewEncoder(wr)
+	err := enc.Encode(p)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode projection, %w", err)
+	}
+
+	return nil
+}
+
+// Read decodes a JSON-encoded projection, written by `Projection.Write`, from 'r'.
+func Read(r io.Reader) (*Projection, error) {
+
+	var p *Projection
+
+	dec := json.NewDecoder(r)
+	err := dec.Decode(&p)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode projection, %w", err)
+	}
+
+	if p == nil {
+		return nil, fmt.Errorf("Missing projection")
+	}
+
+	err = p.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid projection, %w", err)
+	}
+
+	return p, nil
+}
+
+// Apply returns the result of projecting 'vector' using 'p'.
+func Apply[T Float](p *Projection, vector []T) ([]T, error) {
+
+	if len(vector) != p.InputDimensions {
+		return nil, fmt.Errorf("Embeddings have %d dimensions, projection expects %d", len(vector), p.InputDimensions)
+	}
+
+	x := make([]float64, len(vector))
+
+	for i, f := range vector {
+
+		x[i] = float64(f)
+
+		if p.Mean != nil {
+			x[i] -= p.Mean[i]
+		}
+	}
+
+	projected := make([]T, p.OutputDimensions)
+
+	for i, row := range p.Matrix {
+
+		v := dot(row, x)
+
+		if p.Bias != nil {
+			v += p.Bias[i]
+		}
+
+		projected[i] = T(v)
+	}
+
+	return projected, nil
+}
+
+// mean returns the element-wise mean of 'vectors', which must all have the same dimensions, as float64 values.
+func mean[T Float](vectors [][]T) ([]float64, error) {
+
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("No vectors")
+	}
+
+	dims := len(vectors[0])
+
+	if dims == 0 {
+		return nil, fmt.Errorf("Vectors have zero dimensions")
+	}
+
+	m := make([]float64, dims)
+
+	for idx, v := range vectors {
+
+		if len(v) != dims {
+			return nil, fmt.Errorf("Vector %d has %d dimensions, expected %d", idx, len(v), dims)
+		}
+
+		for i, f := range v {
+			m[i] += float64(f)
+		}
+	}
+
+	for i := range m {
+		m[i] /= float64(len(vectors))
+	}
+
+	return m, nil
+}
+
+// centered returns 'vectors' as float64 values with 'm' subtracted.
+func centered[T Float](vectors [][]T, m []float64) [][]float64 {
+
+	c := make([][]float64, len(vectors))
+
+	for idx, v := range vectors {
+
+		c[idx] = make([]float64, len(v))
+
+		for i, f := range v {
+			c[idx][i] = float64(f) - m[i]
+		}
+	}
+
+	return c
+}
+
+// gram returns the (symmetric) matrix Aᵀ·B for the rows of 'a' and 'b'.
+func gram(a [][]float64, b [][]float64) [][]float64 {
+
+	rows := len(a[0])
+	cols := len(b[0])
+
+	g := make([][]float64, rows)
+
+	for i := range g {
+		g[i] = make([]float64, cols)
+	}
+
+	for n := range a {
+
+		x := a[n]
+		y := b[n]
+
+		for i, xi := range x {
+
+			if xi == 0 {
+				continue
+			}
+
+			row := g[i]
+
+			for j, yj := range y {
+				row[j] += xi * yj
+			}
+		}
+	}
+
+	return g
+}
+
+func dot(a []float64, b []float64) float64 {
+
+	sum := 0.0
+
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+
+	return sum
+}
+
+func norm(a []float64) float64 {
+	return math.Sqrt(dot(a, a))
+}
@@ -0,0 +1,88 @@
+package projection
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+
+	p, err := NewLinearProjection([][]float64{{1, 0, 0}, {0, 1, 1}}, []float64{0, 10})
+
+	if err != nil {
+		t.Fatalf("Failed to create projection, %v", err)
+	}
+
+	projected, err := Apply(p, []float32{1, 2, 3})
+
+	if err != nil {
+		t.Fatalf("Failed to apply projection, %v", err)
+	}
+
+	if len(projected) != 2 || projected[0] != 1 || projected[1] != 15 {
+		t.Fatalf("Unexpected projection, %v", projected)
+	}
+
+	_, err = Apply(p, []float32{1, 2})
+
+	if err == nil {
+		t.Fatalf("Expected mismatched dimensions to fail")
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	_, err := NewLinearProjection([][]float64{{1, 0, 0}, {0, 1}}, nil)
+
+	if err == nil {
+		t.Fatalf("Expected ragged matrix to fail")
+	}
+
+	_, err = NewLinearProjection([][]float64{{1, 0, 0}}, []float64{1, 2})
+
+	if err == nil {
+		t.Fatalf("Expected mismatched bias to fail")
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+
+	p := &Projection{
+		Method:           METHOD_PCA,
+		InputDimensions:  2,
+		OutputDimensions: 1,
+		Mean:             []float64{0.5, -0.5},
+		Matrix:           [][]float64{{math.Sqrt2 / 2, math.Sqrt2 / 2}},
+	}
+
+	var buf bytes.Buffer
+
+	err := p.Write(&buf)
+
+	if err != nil {
+		t.Fatalf("Failed to write projection, %v", err)
+	}
+
+	p2, err := Read(&buf)
+
+	if err != nil {
+		t.Fatalf("Failed to read projection, %v", err)
+	}
+
+	if p2.Method != METHOD_PCA || p2.Matrix[0][1] != p.Matrix[0][1] || p2.Mean[1] != -0.5 {
+		t.Fatalf("Unexpected projection, %+v", p2)
+	}
+
+	_, err = Read(bytes.NewBufferString(`{"input_dimensions":2,"output_dimensions":1,"matrix":[[1]]}`))
+
+	if err == nil {
+		t.Fatalf("Expected invalid projection to fail")
+	}
+
+	_, err = Read(bytes.NewBufferString(`null`))
+
+	if err == nil {
+		t.Fatalf("Expected null projection to fail")
+	}
+}
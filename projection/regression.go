@@ -0,0 +1,155 @@
+package projection
+
+import (
+	"fmt"
+	"math"
+)
+
+// FitRegression returns a `Projection` which maps embeddings in 'source' on to the corresponding embeddings in 'target', found
+// using ridge regression with regularization strength 'lambda'. For example, it can be used to align embeddings derived by a new
+// model with the embeddings derived by the model it replaces, for the same inputs. 'source' and 'target' must have the same number
+// of embeddings. A 'lambda' of 0 (no regularization) requires at least as many embeddings as 'source' has dimensions.
+func FitRegression[T Float](source [][]T, target [][]T, lambda float64) (*Projection, error) {
+
+	if len(source) != len(target) {
+		return nil, fmt.Errorf("Source has %d embeddings but target has %d", len(source), len(target))
+	}
+
+	if lambda < 0 {
+		return nil, fmt.Errorf("Invalid lambda, must be greater than or equal to 0")
+	}
+
+	source_mean, err := mean(source)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid source embeddings, %w", err)
+	}
+
+	target_mean, err := mean(target)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid target embeddings, %w", err)
+	}
+
+	x := centered(source, source_mean)
+	y := centered(target, target_mean)
+
+	// Solve (XᵀX + λI) W = XᵀY
+
+	a := gram(x, x)
+
+	for i := range a {
+		a[i][i] += lambda
+	}
+
+	b := gram(x, y)
+
+	l, err := cholesky(a)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fit regression, consider increasing lambda or the number of embeddings, %w", err)
+	}
+
+	in_dims := len(source_mean)
+	out_dims := len(target_mean)
+
+	// W has one column for each output dimension; the projection matrix has one row for each output dimension
+
+	matrix := make([][]float64, out_dims)
+	col := make([]float64, in_dims)
+
+	for j := range matrix {
+
+		for i := range col {
+			col[i] = b[i][j]
+		}
+
+		matrix[j] = choleskySolve(l, col)
+	}
+
+	p := &Projection{
+		Method:           METHOD_REGRESSION,
+		InputDimensions:  in_dims,
+		OutputDimensions: out_dims,
+		Mean:             source_mean,
+		Matrix:           matrix,
+		Bias:             target_mean,
+	}
+
+	return p, nil
+}
+
+// cholesky returns the lower triangular matrix L such that L·Lᵀ = 'a', which must be symmetric and positive definite.
+func cholesky(a [][]float64) ([][]float64, error) {
+
+	n := len(a)
+	l := make([][]float64, n)
+
+	for i := range l {
+		l[i] = make([]float64, i+1)
+	}
+
+	for i := 0; i < n; i++ {
+
+		for j := 0; j <= i; j++ {
+
+			sum := a[i][j]
+
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+
+			if i == j {
+
+				if sum <= 1e-12 {
+					return nil, fmt.Errorf("Matrix is not positive definite")
+				}
+
+				l[i][i] = math.Sqrt(sum)
+				continue
+			}
+
+			l[i][j] = sum / l[j][j]
+		}
+	}
+
+	return l, nil
+}
+
+// choleskySolve returns x such that L·Lᵀ·x = 'b'.
+func choleskySolve(l [][]float64, b []float64) []float64 {
+
+	n := len(l)
+
+	// Forward substitution: L·y = b
+
+	y := make([]float64, n)
+
+	for i := 0; i < n; i++ {
+
+		sum := b[i]
+
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * y[k]
+		}
+
+		y[i] = sum / l[i][i]
+	}
+
+	// Back substitution: Lᵀ·x = y
+
+	x := make([]float64, n)
+
+	for i := n - 1; i >= 0; i-- {
+
+		sum := y[i]
+
+		for k := i + 1; k < n; k++ {
+			sum -= l[k][i] * x[k]
+		}
+
+		x[i] = sum / l[i][i]
+	}
+
+	return x
+}
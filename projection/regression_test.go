@@ -0,0 +1,77 @@
+package projection
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestFitRegression(t *testing.T) {
+
+	r := rand.New(rand.NewPCG(5, 6))
+
+	// The "new" model's embeddings are a linear transformation of the "old" model's embeddings
+
+	w := [][]float64{
+		{0.5, -1, 2, 0},
+		{1, 1, 0, -0.5},
+		{0, 0.25, 1, 1},
+	}
+
+	bias := []float64{1, -2, 0.5}
+
+	source := make([][]float64, 100)
+	target := make([][]float64, 100)
+
+	for i := range source {
+
+		source[i] = make([]float64, 4)
+
+		for j := range source[i] {
+			source[i][j] = r.NormFloat64()
+		}
+
+		target[i] = make([]float64, 3)
+
+		for j, row := range w {
+			target[i][j] = dot(row, source[i]) + bias[j]
+		}
+	}
+
+	p, err := FitRegression(source, target, 0)
+
+	if err != nil {
+		t.Fatalf("Failed to fit regression, %v", err)
+	}
+
+	test := []float64{0.1, 0.2, 0.3, 0.4}
+
+	projected, err := Apply(p, test)
+
+	if err != nil {
+		t.Fatalf("Failed to apply projection, %v", err)
+	}
+
+	for j, row := range w {
+
+		expected := dot(row, test) + bias[j]
+
+		if math.Abs(projected[j]-expected) > 1e-6 {
+			t.Fatalf("Unexpected projection for dimension %d, %f, expected %f", j, projected[j], expected)
+		}
+	}
+
+	// Too few samples without regularization
+
+	_, err = FitRegression(source[:2], target[:2], 0)
+
+	if err == nil {
+		t.Fatalf("Expected underdetermined regression to fail")
+	}
+
+	_, err = FitRegression(source[:2], target[:2], 0.1)
+
+	if err != nil {
+		t.Fatalf("Failed to fit regularized regression, %v", err)
+	}
+}
@@ -0,0 +1,392 @@
+package embeddings
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// Scalar quantization to signed 8-bit integers.
+	QUANTIZE_INT8 string = "int8"
+	// Scalar quantization to unsigned 8-bit integers.
+	QUANTIZE_UINT8 string = "uint8"
+	// Binary (sign) quantization, packed in to bytes.
+	QUANTIZE_BINARY string = "binary"
+)
+
+// QuantizationParameters defines the parameters used to quantize (and dequantize) embeddings.
+type QuantizationParameters struct {
+	// The type of quantization. Valid options are "int8", "uint8" and "binary".
+	Type string `json:"type"`
+	// The number of dimensions in the source (unquantized) embeddings.
+	Dimensions int `json:"dimensions"`
+	// The per-dimension lower bounds used for scalar quantization. Values below this are clamped.
+	Min []float64 `json:"min,omitempty"`
+	// The per-dimension upper bounds used for scalar quantization. Values above this are clamped.
+	Max []float64 `json:"max,omitempty"`
+}
+
+// QuantizedEmbeddings defines quantized embeddings and the parameters used to derive them.
+type QuantizedEmbeddings struct {
+	// The parameters used to quantize the embeddings.
+	Parameters *QuantizationParameters `json:"parameters"`
+	// The quantized embeddings. Signed (int8) values are stored as their two's complement byte value and
+	// binary values are packed 8 dimensions to a byte, most significant bit first.
+	Data []byte `json:"data"`
+}
+
+// NewScalarQuantizationParameters returns a new `QuantizationParameters` instance for "int8" or "uint8" quantization using the
+// per-dimension ranges defined by 'min_values' and 'max_values'. Use the `CalibrateMinMax` or `CalibratePercentile` methods to derive ranges.
+func NewScalarQuantizationParameters(quantization string, min_values []float64, max_values []float64) (*QuantizationParameters, error) {
+
+	switch quantization {
+	case QUANTIZE_INT8, QUANTIZE_UINT8:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported scalar quantization '%s'", quantization)
+	}
+
+	if len(min_values) == 0 || len(min_values) != len(max_values) {
+		return nil, fmt.Errorf("Minimum and maximum ranges must be non-empty and have the same dimensions")
+	}
+
+	p := &QuantizationParameters{
+		Type:       quantization,
+		Dimensions: len(min_values),
+		Min:        min_values,
+		Max:        max_values,
+	}
+
+	err := p.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// NewBinaryQuantizationParameters returns a new `QuantizationParameters` instance for binary quantization of embeddings with 'dimensions'.
+func NewBinaryQuantizationParameters(dimensions int) (*QuantizationParameters, error) {
+
+	if dimensions < 1 {
+		return nil, fmt.Errorf("Invalid dimensions")
+	}
+
+	p := &QuantizationParameters{
+		Type:       QUANTIZE_BINARY,
+		Dimensions: dimensions,
+	}
+
+	return p, nil
+}
+
+// Validate returns an error if 'p' defines an unsupported type, negative dimensions or, for scalar quantization,
+// ranges whose dimensions do not match or which are not finite and ordered (minimum less than or equal to maximum).
+func (p *QuantizationParameters) Validate() error {
+
+	if p.Dimensions < 0 {
+		return fmt.Errorf("Invalid dimensions")
+	}
+
+	switch p.Type {
+	case QUANTIZE_INT8, QUANTIZE_UINT8:
+
+		if len(p.Min) != p.Dimensions || len(p.Max) != p.Dimensions {
+			return fmt.Errorf("Minimum (%d) and maximum (%d) ranges must have %d dimensions", len(p.Min), len(p.Max), p.Dimensions)
+		}
+
+		for i := range p.Min {
+
+			if math.IsNaN(p.Min[i]) || math.IsInf(p.Min[i], 0) || math.IsNaN(p.Max[i]) || math.IsInf(p.Max[i], 0) {
+				return fmt.Errorf("Minimum and maximum values must be finite for dimension %d", i)
+			}
+
+			if p.Min[i] > p.Max[i] {
+				return fmt.Errorf("Minimum value is greater than maximum value for dimension %d", i)
+			}
+		}
+
+	case QUANTIZE_BINARY:
+		// pass
+	default:
+		return fmt.Errorf("Invalid or unsupported quantization '%s'", p.Type)
+	}
+
+	return nil
+}
+
+// CalibrateMinMax returns the per-dimension minimum and maximum values of 'vectors', all of which must have the same dimensions.
+func CalibrateMinMax[T Float](vectors [][]T) ([]float64, []float64, error) {
+	return CalibratePercentile(vectors, 0, 100)
+}
+
+// CalibratePercentile returns the per-dimension 'lower' and 'upper' percentiles (0-100) of 'vectors', all of which must have the
+// same dimensions. Using percentiles (for example 1 and 99) rather than the absolute minimum and maximum values prevents outliers
+// from reducing the resolution of the quantized values.
+func CalibratePercentile[T Float](vectors [][]T, lower float64, upper float64) ([]float64, []float64, error) {
+
+	if len(vectors) == 0 {
+		return nil, nil, fmt.Errorf("No vectors to calibrate")
+	}
+
+	if lower < 0 || upper > 100 || lower > upper {
+		return nil, nil, fmt.Errorf("Invalid percentiles, must be between 0 and 100 and lower must not exceed upper")
+	}
+
+	dims := len(vectors[0])
+	min_values := make([]float64, dims)
+	max_values := make([]float64, dims)
+
+	column := make([]float64, len(vectors))
+
+	for i := 0; i < dims; i++ {
+
+		for idx, v := range vectors {
+
+			if len(v) != dims {
+				return nil, nil, fmt.Errorf("Vector at offset %d has %d dimensions, expected %d", idx, len(v), dims)
+			}
+
+			column[idx] = float64(v[i])
+		}
+
+		slices.Sort(column)
+
+		min_values[i] = percentile(column, lower)
+		max_values[i] = percentile(column, upper)
+	}
+
+	return min_values, max_values, nil
+}
+
+// Quantize returns the quantized representation of 'vector' using 'params'.
+func Quantize[T Float](vector []T, params *QuantizationParameters) (*QuantizedEmbeddings, error) {
+
+	if params == nil {
+		return nil, fmt.Errorf("Missing quantization parameters")
+	}
+
+	err := params.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid quantization parameters, %w", err)
+	}
+
+	if len(vector) != params.Dimensions {
+		return nil, fmt.Errorf("Vector has %d dimensions, expected %d", len(vector), params.Dimensions)
+	}
+
+	var data []byte
+
+	switch params.Type {
+	case QUANTIZE_INT8, QUANTIZE_UINT8:
+
+		data = make([]byte, len(vector))
+
+		for i, f := range vector {
+
+			level := scalarLevel(float64(f), params.Min[i], params.Max[i])
+
+			if params.Type == QUANTIZE_INT8 {
+				data[i] = byte(int8(level - 128))
+			} else {
+				data[i] = byte(level)
+			}
+		}
+
+	case QUANTIZE_BINARY:
+
+		data = make([]byte, (len(vector)+7)/8)
+
+		for i, f := range vector {
+
+			if f > 0 {
+				data[i/8] |= 0x80 >> (i % 8)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported quantization '%s'", params.Type)
+	}
+
+	q := &QuantizedEmbeddings{
+		Parameters: params,
+		Data:       data,
+	}
+
+	return q, nil
+}
+
+// Dequantize returns the (approximate) floating point representation of 'q'. Binary embeddings are
+// dequantized to -1.0 and 1.0 values.
+func Dequantize[T Float](q *QuantizedEmbeddings) ([]T, error) {
+
+	params := q.Parameters
+
+	if params == nil {
+		return nil, fmt.Errorf("Missing quantization parameters")
+	}
+
+	err := params.Validate()
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid quantization parameters, %w", err)
+	}
+
+	vector := make([]T, params.Dimensions)
+
+	switch params.Type {
+	case QUANTIZE_INT8, QUANTIZE_UINT8:
+
+		if len(q.Data) != params.Dimensions {
+			return nil, fmt.Errorf("Quantized data has %d values, expected %d", len(q.Data), params.Dimensions)
+		}
+
+		for i, b := range q.Data {
+
+			level := int(b)
+
+			if params.Type == QUANTIZE_INT8 {
+				level = int(int8(b)) + 128
+			}
+
+			scale := (params.Max[i] - params.Min[i]) / 255.0
+			vector[i] = T(params.Min[i] + float64(level)*scale)
+		}
+
+	case QUANTIZE_BINARY:
+
+		if len(q.Data) != (params.Dimensions+7)/8 {
+			return nil, fmt.Errorf("Quantized data has %d bytes, expected %d", len(q.Data), (params.Dimensions+7)/8)
+		}
+
+		for i := range vector {
+
+			if q.Data[i/8]&(0x80>>(i%8)) != 0 {
+				vector[i] = 1.0
+			} else {
+				vector[i] = -1.0
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported quantization '%s'", params.Type)
+	}
+
+	return vector, nil
+}
+
+// Int8 returns the quantized data of 'q' as signed integers. It is an error if 'q' was not quantized using "int8".
+func (q *QuantizedEmbeddings) Int8() ([]int8, error) {
+
+	if q.Parameters == nil {
+		return nil, fmt.Errorf("Missing quantization parameters")
+	}
+
+	if q.Parameters.Type != QUANTIZE_INT8 {
+		return nil, fmt.Errorf("Embeddings are not int8-quantized")
+	}
+
+	values := make([]int8, len(q.Data))
+
+	for i, b := range q.Data {
+		values[i] = int8(b)
+	}
+
+	return values, nil
+}
+
+// HammingDistance returns the number of bits which differ between 'a' and 'b', which must have the same length.
+func HammingDistance(a []byte, b []byte) (int, error) {
+
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("Inputs have different lengths, %d and %d", len(a), len(b))
+	}
+
+	d := 0
+
+	for i := range a {
+		d += bits.OnesCount8(a[i] ^ b[i])
+	}
+
+	return d, nil
+}
+
+// QuantizedDistance returns the distance between 'a' and 'b' which must have been quantized using the same type and dimensions.
+// For binary embeddings this is the Hamming distance. For scalar (int8 and uint8) embeddings this is the cosine distance
+// (1 - cosine similarity) of their dequantized values.
+func QuantizedDistance(a *QuantizedEmbeddings, b *QuantizedEmbeddings) (float64, error) {
+
+	if a.Parameters == nil || b.Parameters == nil {
+		return 0, fmt.Errorf("Missing quantization parameters")
+	}
+
+	if a.Parameters.Type != b.Parameters.Type || a.Parameters.Dimensions != b.Parameters.Dimensions {
+		return 0, fmt.Errorf("Embeddings were quantized using different parameters")
+	}
+
+	switch a.Parameters.Type {
+	case QUANTIZE_BINARY:
+
+		d, err := HammingDistance(a.Data, b.Data)
+
+		if err != nil {
+			return 0, err
+		}
+
+		return float64(d), nil
+
+	case QUANTIZE_INT8, QUANTIZE_UINT8:
+
+		v_a, err := Dequantize[float64](a)
+
+		if err != nil {
+			return 0, err
+		}
+
+		v_b, err := Dequantize[float64](b)
+
+		if err != nil {
+			return 0, err
+		}
+
+		sim, err := CosineSimilarity(v_a, v_b)
+
+		if err != nil {
+			return 0, err
+		}
+
+		return 1.0 - sim, nil
+
+	default:
+		return 0, fmt.Errorf("Invalid or unsupported quantization '%s'", a.Parameters.Type)
+	}
+}
+
+// scalarLevel maps 'f' to a value between 0 and 255 relative to the range 'min_value' to 'max_value'.
+func scalarLevel(f float64, min_value float64, max_value float64) int {
+
+	if max_value == min_value {
+		return 0
+	}
+
+	level := math.Round((f - min_value) / (max_value - min_value) * 255.0)
+	return int(math.Max(0, math.Min(255, level)))
+}
+
+// percentile returns the 'p' (0-100) percentile of 'sorted' using linear interpolation.
+func percentile(sorted []float64, p float64) float64 {
+
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+
+	rank := (p / 100.0) * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
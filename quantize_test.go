@@ -0,0 +1,202 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestScalarQuantization(t *testing.T) {
+
+	vectors := [][]float32{
+		{-1.0, 0.0, 0.5},
+		{1.0, 2.0, 0.5},
+		{0.0, 1.0, 0.5},
+	}
+
+	min_values, max_values, err := CalibrateMinMax(vectors)
+
+	if err != nil {
+		t.Fatalf("Failed to calibrate vectors, %v", err)
+	}
+
+	for _, quantization := range []string{QUANTIZE_INT8, QUANTIZE_UINT8} {
+
+		params, err := NewScalarQuantizationParameters(quantization, min_values, max_values)
+
+		if err != nil {
+			t.Fatalf("Failed to create %s parameters, %v", quantization, err)
+		}
+
+		for _, v := range vectors {
+
+			q, err := Quantize(v, params)
+
+			if err != nil {
+				t.Fatalf("Failed to quantize vector using %s, %v", quantization, err)
+			}
+
+			dq, err := Dequantize[float32](q)
+
+			if err != nil {
+				t.Fatalf("Failed to dequantize vector using %s, %v", quantization, err)
+			}
+
+			for i := range v {
+
+				// Allow for half a quantization step
+
+				tolerance := (max_values[i]-min_values[i])/255.0/2.0 + 1e-6
+
+				if math.Abs(float64(dq[i]-v[i])) > tolerance {
+					t.Fatalf("Unexpected dequantized value for %s at %d, expected %f but got %f", quantization, i, v[i], dq[i])
+				}
+			}
+		}
+	}
+
+	params, _ := NewScalarQuantizationParameters(QUANTIZE_INT8, min_values, max_values)
+	q, _ := Quantize(vectors[0], params)
+
+	values, err := q.Int8()
+
+	if err != nil {
+		t.Fatalf("Failed to read int8 values, %v", err)
+	}
+
+	if values[0] != -128 || values[1] != -128 {
+		t.Fatalf("Unexpected int8 values, %v", values)
+	}
+}
+
+func TestCalibratePercentile(t *testing.T) {
+
+	vectors := make([][]float64, 0)
+
+	for i := 0; i <= 100; i++ {
+		vectors = append(vectors, []float64{float64(i)})
+	}
+
+	min_values, max_values, err := CalibratePercentile(vectors, 5, 95)
+
+	if err != nil {
+		t.Fatalf("Failed to calibrate vectors, %v", err)
+	}
+
+	if min_values[0] != 5 || max_values[0] != 95 {
+		t.Fatalf("Unexpected percentiles, %v %v", min_values, max_values)
+	}
+}
+
+func TestBinaryQuantization(t *testing.T) {
+
+	params, err := NewBinaryQuantizationParameters(10)
+
+	if err != nil {
+		t.Fatalf("Failed to create binary parameters, %v", err)
+	}
+
+	a := []float32{1, -1, 1, -1, 1, -1, 1, -1, 1, 1}
+	b := []float32{1, 1, 1, -1, 1, -1, 1, -1, 1, -1}
+
+	q_a, err := Quantize(a, params)
+
+	if err != nil {
+		t.Fatalf("Failed to quantize vector, %v", err)
+	}
+
+	if len(q_a.Data) != 2 || q_a.Data[0] != 0xAA || q_a.Data[1] != 0xC0 {
+		t.Fatalf("Unexpected binary data, %x", q_a.Data)
+	}
+
+	q_b, err := Quantize(b, params)
+
+	if err != nil {
+		t.Fatalf("Failed to quantize vector, %v", err)
+	}
+
+	d, err := QuantizedDistance(q_a, q_b)
+
+	if err != nil {
+		t.Fatalf("Failed to derive distance, %v", err)
+	}
+
+	if d != 2 {
+		t.Fatalf("Unexpected Hamming distance, %f", d)
+	}
+
+	dq, err := Dequantize[float32](q_a)
+
+	if err != nil {
+		t.Fatalf("Failed to dequantize vector, %v", err)
+	}
+
+	for i := range a {
+
+		if dq[i] != a[i] {
+			t.Fatalf("Unexpected dequantized value at %d, %f", i, dq[i])
+		}
+	}
+}
+
+func TestQuantizationParametersValidate(t *testing.T) {
+
+	// Parameters decoded from JSON are not guaranteed to be consistent
+
+	tests := map[string]string{
+		"short ranges":        `{"type": "int8", "dimensions": 3, "min": [0, 0], "max": [1, 1]}`,
+		"negative dimensions": `{"type": "binary", "dimensions": -1}`,
+		"invalid type":        `{"type": "int4", "dimensions": 2}`,
+		"unordered ranges":    `{"type": "int8", "dimensions": 3, "min": [0, 2, 0], "max": [1, 1, 1]}`,
+	}
+
+	for name, enc := range tests {
+
+		var params *QuantizationParameters
+
+		err := json.Unmarshal([]byte(enc), &params)
+
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s parameters, %v", name, err)
+		}
+
+		if params.Validate() == nil {
+			t.Fatalf("Expected %s parameters to fail validation", name)
+		}
+
+		_, err = Quantize([]float32{1, 2, 3}, params)
+
+		if err == nil {
+			t.Fatalf("Expected quantize with %s parameters to fail", name)
+		}
+
+		_, err = Dequantize[float32](&QuantizedEmbeddings{Parameters: params, Data: []byte{1, 2, 3}})
+
+		if err == nil {
+			t.Fatalf("Expected dequantize with %s parameters to fail", name)
+		}
+	}
+
+	non_finite := &QuantizationParameters{
+		Type:       QUANTIZE_UINT8,
+		Dimensions: 2,
+		Min:        []float64{0, math.NaN()},
+		Max:        []float64{1, math.Inf(1)},
+	}
+
+	if non_finite.Validate() == nil {
+		t.Fatalf("Expected non-finite parameters to fail validation")
+	}
+
+	_, err := Dequantize[float32](&QuantizedEmbeddings{Data: []byte{1}})
+
+	if err == nil {
+		t.Fatalf("Expected dequantize with missing parameters to fail")
+	}
+
+	_, err = (&QuantizedEmbeddings{Data: []byte{1}}).Int8()
+
+	if err == nil {
+		t.Fatalf("Expected int8 values with missing parameters to fail")
+	}
+}
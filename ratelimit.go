@@ -0,0 +1,350 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitEmbedder implements the `Embedder` interface by limiting the rate (requests per second and estimated tokens
+// per minute) and concurrency of requests sent to another `Embedder` instance. Requests block until they are allowed
+// or their context is done.
+type RateLimitEmbedder[T Float] struct {
+	Embedder[T]
+	embedder        Embedder[T]
+	requests        *tokenBucket
+	tokens          *tokenBucket
+	chars_per_token int
+	slots           chan struct{}
+	adaptive        bool
+	rps             float64
+	min_rps         float64
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "ratelimit", NewRateLimitEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "ratelimit32", NewRateLimitEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "ratelimit64", NewRateLimitEmbedder[float64])
+}
+
+// NewRateLimitEmbedder creates a new `RateLimitEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	ratelimit://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `rps` – The maximum number of requests per second. Default is 0 (no limit).
+// * `burst` – The maximum number of requests which may be sent at once when `rps` is set. Default is `rps`, rounded up.
+// * `tpm` – The maximum number of (estimated) text tokens per minute. Default is 0 (no limit).
+// * `chars-per-token` – The number of bytes of text per token used to estimate the number of tokens in a request. Default is 4.
+// * `max-concurrency` – The maximum number of concurrent requests. Default is 0 (no limit).
+// * `adaptive` – A boolean flag indicating that `rps` should be halved each time the underlying embedder returns a rate-limit
+// (HTTP 429 or gRPC "ResourceExhausted") error and then gradually restored as requests succeed. Requires `rps`. Default is true.
+// * `min-rps` – The minimum requests per second when adapting to rate-limit errors. Default is 10% of `rps`.
+func NewRateLimitEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &RateLimitEmbedder[T]{
+		embedder:        emb,
+		chars_per_token: 4,
+		adaptive:        true,
+	}
+
+	float_params := map[string]*float64{
+		"rps":     &e.rps,
+		"min-rps": &e.min_rps,
+	}
+
+	for k, ptr := range float_params {
+
+		if !q.Has(k) {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(q.Get(k), 64)
+
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("Invalid ?%s= parameter", k)
+		}
+
+		*ptr = v
+	}
+
+	burst := int(math.Ceil(e.rps))
+	tpm := 0
+	max_concurrency := 0
+
+	int_params := map[string]*int{
+		"burst":           &burst,
+		"tpm":             &tpm,
+		"max-concurrency": &max_concurrency,
+		"chars-per-token": &e.chars_per_token,
+	}
+
+	for k, ptr := range int_params {
+
+		if !q.Has(k) {
+			continue
+		}
+
+		v, err := strconv.Atoi(q.Get(k))
+
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("Invalid ?%s= parameter", k)
+		}
+
+		*ptr = v
+	}
+
+	if e.chars_per_token < 1 {
+		return nil, fmt.Errorf("Invalid ?chars-per-token= parameter, must be greater than 0")
+	}
+
+	if q.Has("adaptive") {
+
+		v, err := strconv.ParseBool(q.Get("adaptive"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?adaptive= parameter, %w", err)
+		}
+
+		e.adaptive = v
+	}
+
+	if e.rps > 0 {
+
+		if !q.Has("min-rps") {
+			e.min_rps = e.rps / 10
+		}
+
+		if e.min_rps <= 0 || e.min_rps > e.rps {
+			return nil, fmt.Errorf("Invalid ?min-rps= parameter, must be greater than 0 and less than or equal to ?rps=")
+		}
+
+		e.requests = newTokenBucket(e.rps, float64(max(1, burst)))
+	}
+
+	if tpm > 0 {
+		e.tokens = newTokenBucket(float64(tpm)/60, float64(tpm))
+	}
+
+	if max_concurrency > 0 {
+		e.slots = make(chan struct{}, max_concurrency)
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings waits until 'req' is allowed by the rate and concurrency limits and then passes it to the underlying embedder.
+func (e *RateLimitEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	tokens := max(1, int(math.Ceil(float64(len(req.Body))/float64(e.chars_per_token))))
+
+	return e.embeddings(ctx, tokens, func() (EmbeddingsResponse[T], error) {
+		return e.embedder.TextEmbeddings(ctx, req)
+	})
+}
+
+// ImageEmbeddings waits until 'req' is allowed by the rate and concurrency limits and then passes it to the underlying embedder.
+// Image requests are not counted against the tokens per minute limit.
+func (e *RateLimitEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, 0, func() (EmbeddingsResponse[T], error) {
+		return e.embedder.ImageEmbeddings(ctx, req)
+	})
+}
+
+// Rate returns the current maximum number of requests per second, which may be lower than the configured rate if the
+// underlying embedder has returned rate-limit errors. Zero means no limit.
+func (e *RateLimitEmbedder[T]) Rate() float64 {
+
+	if e.requests == nil {
+		return 0
+	}
+
+	return e.requests.Rate()
+}
+
+// Close closes the underlying embedder.
+func (e *RateLimitEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *RateLimitEmbedder[T]) embeddings(ctx context.Context, tokens int, fn func() (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	if e.requests != nil {
+
+		err := e.requests.Wait(ctx, 1)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.tokens != nil && tokens > 0 {
+
+		err := e.tokens.Wait(ctx, float64(tokens))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.slots != nil {
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case e.slots <- struct{}{}:
+		}
+
+		defer func() {
+			<-e.slots
+		}()
+	}
+
+	rsp, err := fn()
+
+	if e.adaptive && e.requests != nil {
+		e.adapt(err)
+	}
+
+	return rsp, err
+}
+
+// adapt halves the request rate if 'err' is a rate-limit error, otherwise it increases the rate by 5% of the configured rate.
+func (e *RateLimitEmbedder[T]) adapt(err error) {
+
+	current := e.requests.Rate()
+
+	switch {
+	case err == nil:
+
+		if current < e.rps {
+			e.requests.SetRate(min(e.rps, current+(e.rps*0.05)))
+		}
+
+	case IsRateLimitError(err):
+
+		rate := max(e.min_rps, current/2)
+
+		if rate < current {
+			slog.Warn("Backend is rate limiting requests, reducing rate", "rps", rate, "error", err)
+			e.requests.SetRate(rate)
+		}
+	}
+}
+
+// tokenBucket is a simple token bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst float64) *tokenBucket {
+
+	b := &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+
+	return b
+}
+
+// Wait blocks until 'n' tokens are available, or 'ctx' is done. Requests for more tokens than the size of the
+// bucket wait until the bucket is full.
+func (b *tokenBucket) Wait(ctx context.Context, n float64) error {
+
+	for {
+
+		b.mu.Lock()
+
+		b.refill(time.Now())
+		n = min(n, b.burst)
+
+		if b.tokens >= n {
+			b.tokens -= n
+			b.mu.Unlock()
+			return nil
+		}
+
+		var wait time.Duration
+
+		if b.rate > 0 {
+			wait = time.Duration(((n - b.tokens) / b.rate) * float64(time.Second))
+		}
+
+		b.mu.Unlock()
+
+		// A rate of zero should never happen but don't spin if it does
+
+		wait = max(wait, time.Millisecond)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Rate returns the number of tokens added to the bucket per second.
+func (b *tokenBucket) Rate() float64 {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rate
+}
+
+// SetRate sets the number of tokens added to the bucket per second.
+func (b *tokenBucket) SetRate(rate float64) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.rate = rate
+}
+
+// refill adds tokens accumulated since the last refill. It must be called with the lock held.
+func (b *tokenBucket) refill(now time.Time) {
+
+	elapsed := now.Sub(b.last).Seconds()
+
+	if elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+(elapsed*b.rate))
+		b.last = now
+	}
+}
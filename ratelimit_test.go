@@ -0,0 +1,197 @@
+package embeddings
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimitEmbedderRPS(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "ratelimit://?client-uri=testing://&rps=20&burst=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+
+		_, err := emb.TextEmbeddings(ctx, req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive embeddings, %v", err)
+		}
+	}
+
+	// The first request is allowed immediately and the following four wait 50ms each
+
+	if time.Since(start) < 180*time.Millisecond {
+		t.Fatalf("Requests were not rate limited, took %v", time.Since(start))
+	}
+
+	// Requests stop waiting when their context is cancelled
+
+	cancel_ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	emb.TextEmbeddings(ctx, req)
+
+	_, err = emb.TextEmbeddings(cancel_ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected context error")
+	}
+}
+
+func TestRateLimitEmbedderTPM(t *testing.T) {
+
+	ctx := context.Background()
+
+	// 6000 tokens per minute is 100 tokens per second; a 400-byte body is 100 tokens
+
+	emb, err := NewEmbedder32(ctx, "ratelimit://?client-uri=testing://&tpm=6000")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	bucket := emb.(*RateLimitEmbedder[float32]).tokens
+	bucket.tokens = 0
+
+	req := &EmbeddingsRequest{
+		Body: make([]byte, 400),
+	}
+
+	start := time.Now()
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if time.Since(start) < 900*time.Millisecond {
+		t.Fatalf("Request was not rate limited, took %v", time.Since(start))
+	}
+
+	// Image requests are not counted
+
+	start = time.Now()
+
+	_, err = emb.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive image embeddings, %v", err)
+	}
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("Image request was rate limited, took %v", time.Since(start))
+	}
+}
+
+func TestRateLimitEmbedderConcurrency(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "ratelimit://?client-uri=testing://%3Fdelay=50&max-concurrency=1")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	start := time.Now()
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 3; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			req := &EmbeddingsRequest{
+				Body: []byte("Hello world"),
+			}
+
+			_, err := emb.TextEmbeddings(ctx, req)
+
+			if err != nil {
+				t.Errorf("Failed to derive embeddings, %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if time.Since(start) < 150*time.Millisecond {
+		t.Fatalf("Requests were not serialized, took %v", time.Since(start))
+	}
+}
+
+func TestRateLimitEmbedderAdaptive(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "ratelimit://?client-uri=testing://%3Ferror=ratelimit%26failures=2&rps=1000&min-rps=300")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	rl := emb.(*RateLimitEmbedder[float32])
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	emb.TextEmbeddings(ctx, req)
+
+	if rl.Rate() != 500 {
+		t.Fatalf("Expected rate to be halved, got %f", rl.Rate())
+	}
+
+	emb.TextEmbeddings(ctx, req)
+
+	if rl.Rate() != 300 {
+		t.Fatalf("Expected rate to be reduced to minimum, got %f", rl.Rate())
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if rl.Rate() != 350 {
+		t.Fatalf("Expected rate to increase, got %f", rl.Rate())
+	}
+}
+
+func TestRateLimitEmbedderInvalid(t *testing.T) {
+
+	ctx := context.Background()
+
+	for _, uri := range []string{
+		"ratelimit://",
+		"ratelimit://?client-uri=testing://&rps=-1",
+		"ratelimit://?client-uri=testing://&rps=10&min-rps=20",
+		"ratelimit://?client-uri=testing://&chars-per-token=0",
+	} {
+
+		_, err := NewEmbedder32(ctx, uri)
+
+		if err == nil {
+			t.Fatalf("Expected %s to fail", uri)
+		}
+	}
+}
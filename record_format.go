@@ -0,0 +1,233 @@
+package embeddings
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Each record written by `RecordEncoder` starts with this (4-byte) magic string.
+const RECORD_MAGIC string = "EMBR"
+
+// The current version of the record format.
+const RECORD_VERSION uint8 = 1
+
+// The maximum length, in bytes, of a record following the length field. Longer records are not written and, when
+// decoding, are treated as corrupt rather than allocating arbitrarily large buffers.
+const RECORD_MAX_LENGTH uint32 = 64 << 20
+
+// RecordEncoder writes `EmbeddingsResponse` instances as length-prefixed binary records. Records are
+// self-describing and can be concatenated. Each record is encoded as follows (all integers are little-endian):
+//
+//	magic       [4]byte  "EMBR"
+//	length      uint32   The number of bytes in the record following this field
+//	version     uint8    The version of the record format (1)
+//	width       uint8    The size, in bytes, of each embedding value (4 for float32, 8 for float64)
+//	id          uint16 length followed by UTF-8 bytes
+//	model       uint16 length followed by UTF-8 bytes
+//	precision   uint16 length followed by UTF-8 bytes
+//	created     int64    Unix timestamp
+//	dimensions  uint32   The number of embedding values
+//	embeddings  [dimensions * width]byte
+type RecordEncoder[T Float] struct {
+	writer io.Writer
+}
+
+// RecordDecoder reads records written by `RecordEncoder`.
+type RecordDecoder[T Float] struct {
+	reader *bufio.Reader
+}
+
+// NewRecordEncoder returns a new `RecordEncoder` which writes to 'wr'.
+func NewRecordEncoder[T Float](wr io.Writer) *RecordEncoder[T] {
+
+	enc := &RecordEncoder[T]{
+		writer: wr,
+	}
+
+	return enc
+}
+
+// Encode writes 'rsp' as a single record.
+func (enc *RecordEncoder[T]) Encode(rsp EmbeddingsResponse[T]) error {
+
+	var body bytes.Buffer
+
+	body.WriteByte(RECORD_VERSION)
+	body.WriteByte(byte(floatWidth[T]()))
+
+	for _, s := range []string{rsp.Id(), rsp.Model(), rsp.Precision()} {
+
+		if len(s) > math.MaxUint16 {
+			return fmt.Errorf("String value exceeds maximum length")
+		}
+
+		binary.Write(&body, binary.LittleEndian, uint16(len(s)))
+		body.WriteString(s)
+	}
+
+	embeddings := rsp.Embeddings()
+
+	binary.Write(&body, binary.LittleEndian, rsp.Created())
+	binary.Write(&body, binary.LittleEndian, uint32(len(embeddings)))
+	body.Write(floatBytes(embeddings))
+
+	if uint64(body.Len()) > uint64(RECORD_MAX_LENGTH) {
+		return fmt.Errorf("Record exceeds maximum length")
+	}
+
+	header := make([]byte, 8)
+	copy(header, RECORD_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:], uint32(body.Len()))
+
+	_, err := enc.writer.Write(append(header, body.Bytes()...))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write record, %w", err)
+	}
+
+	return nil
+}
+
+// NewRecordDecoder returns a new `RecordDecoder` which reads from 'r'.
+func NewRecordDecoder[T Float](r io.Reader) *RecordDecoder[T] {
+
+	dec := &RecordDecoder[T]{
+		reader: bufio.NewReader(r),
+	}
+
+	return dec
+}
+
+// Decode reads the next record, converting its embeddings to T. It returns `io.EOF` when there are no more records.
+func (dec *RecordDecoder[T]) Decode() (*CommonEmbeddingsResponse[T], error) {
+
+	header := make([]byte, 8)
+
+	_, err := io.ReadFull(dec.reader, header)
+
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record header, %w", err)
+	}
+
+	if string(header[0:4]) != RECORD_MAGIC {
+		return nil, fmt.Errorf("Invalid record magic string")
+	}
+
+	length := binary.LittleEndian.Uint32(header[4:])
+
+	if length > RECORD_MAX_LENGTH {
+		return nil, fmt.Errorf("Record length (%d) exceeds maximum length", length)
+	}
+
+	// Read through a LimitReader, rather than allocating 'length' bytes up front, so that a truncated
+	// stream only allocates as much memory as it actually contains
+
+	body, err := io.ReadAll(io.LimitReader(dec.reader, int64(length)))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record, %w", err)
+	}
+
+	if len(body) != int(length) {
+		return nil, fmt.Errorf("Failed to read record, %w", io.ErrUnexpectedEOF)
+	}
+
+	r := bytes.NewReader(body)
+
+	var version uint8
+	var width uint8
+
+	err = binary.Read(r, binary.LittleEndian, &version)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record version, %w", err)
+	}
+
+	err = binary.Read(r, binary.LittleEndian, &width)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record version, %w", err)
+	}
+
+	if version != RECORD_VERSION {
+		return nil, fmt.Errorf("Unsupported record version %d", version)
+	}
+
+	values := make([]string, 3)
+
+	for i := range values {
+
+		var length uint16
+
+		err := binary.Read(r, binary.LittleEndian, &length)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read record, %w", err)
+		}
+
+		if int(length) > r.Len() {
+			return nil, fmt.Errorf("Failed to read record, string length (%d) exceeds remaining record length", length)
+		}
+
+		buf := make([]byte, length)
+
+		_, err = io.ReadFull(r, buf)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read record, %w", err)
+		}
+
+		values[i] = string(buf)
+	}
+
+	var created int64
+	var dimensions uint32
+
+	err = binary.Read(r, binary.LittleEndian, &created)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record, %w", err)
+	}
+
+	err = binary.Read(r, binary.LittleEndian, &dimensions)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record, %w", err)
+	}
+
+	if uint64(dimensions)*uint64(width) != uint64(r.Len()) {
+		return nil, fmt.Errorf("Failed to read record embeddings, %d dimensions of width %d do not match remaining record length (%d)", dimensions, width, r.Len())
+	}
+
+	data := make([]byte, int(dimensions)*int(width))
+
+	_, err = io.ReadFull(r, data)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read record embeddings, %w", err)
+	}
+
+	embeddings, err := floatsFromBytes[T](data, int(width))
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &CommonEmbeddingsResponse[T]{
+		CommonId:         values[0],
+		CommonModel:      values[1],
+		CommonPrecision:  values[2],
+		CommonCreated:    created,
+		CommonEmbeddings: embeddings,
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,357 @@
+package embeddings
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ReplayFixture defines a recorded embeddings request and response, stored as a line of JSON in a fixtures file.
+type ReplayFixture[T Float] struct {
+	// The modality of the request ("text" or "image").
+	Modality string `json:"modality"`
+	// The model specified by the request, if any.
+	Model string `json:"model,omitempty"`
+	// The task specified by the request, if any.
+	Task string `json:"task,omitempty"`
+	// The SHA-256 hash of the request body, encoded as a hex string.
+	Hash string `json:"hash"`
+	// The model reported by the response.
+	ResponseModel string `json:"response_model,omitempty"`
+	// The precision reported by the response.
+	Precision string `json:"precision,omitempty"`
+	// The Unix timestamp when the embeddings were created.
+	Created int64 `json:"created"`
+	// The embeddings.
+	Embeddings []T `json:"embeddings"`
+}
+
+// key returns the key used to match requests with fixtures.
+func (f *ReplayFixture[T]) key() string {
+	return fmt.Sprintf("%s#%s#%s#%s", f.Modality, f.Model, f.Task, f.Hash)
+}
+
+func newReplayFixture[T Float](req *EmbeddingsRequest, modality string) *ReplayFixture[T] {
+
+	h := sha256.Sum256(req.Body)
+
+	f := &ReplayFixture[T]{
+		Modality: modality,
+		Model:    req.Model,
+		Task:     req.Task,
+		Hash:     hex.EncodeToString(h[:]),
+	}
+
+	return f
+}
+
+// readReplayFixtures reads the fixtures in 'path', keyed by modality, model, task and hash. If 'path' does not exist
+// and 'missing_ok' is true an empty map is returned.
+func readReplayFixtures[T Float](path string, missing_ok bool) (map[string]*ReplayFixture[T], error) {
+
+	fixtures := make(map[string]*ReplayFixture[T])
+
+	r, err := os.Open(path)
+
+	if err != nil {
+
+		if missing_ok && errors.Is(err, os.ErrNotExist) {
+			return fixtures, nil
+		}
+
+		return nil, fmt.Errorf("Failed to open fixtures, %w", err)
+	}
+
+	defer r.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	for idx := 0; ; idx++ {
+
+		var f *ReplayFixture[T]
+
+		err := dec.Decode(&f)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode fixture, %w", err)
+		}
+
+		if f == nil {
+			return nil, fmt.Errorf("Fixture at offset %d is empty", idx)
+		}
+
+		fixtures[f.key()] = f
+	}
+
+	return fixtures, nil
+}
+
+// RecordEmbedder implements the `Embedder` interface by passing requests to another `Embedder` instance and
+// recording each request and (successful) response in a fixtures file, for use with the `ReplayEmbedder`.
+type RecordEmbedder[T Float] struct {
+	Embedder[T]
+	embedder Embedder[T]
+	mu       sync.Mutex
+	wr       *os.File
+	enc      *json.Encoder
+	recorded map[string]bool
+}
+
+// ReplayEmbedder implements the `Embedder` interface by returning the embeddings recorded in a fixtures file by
+// the `RecordEmbedder`. Requests are matched by modality, model, task and the hash of their body. Requests without
+// a matching fixture fail.
+type ReplayEmbedder[T Float] struct {
+	Embedder[T]
+	fixtures map[string]*ReplayFixture[T]
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "record", NewRecordEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "record32", NewRecordEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "record64", NewRecordEmbedder[float64])
+
+	RegisterEmbedder[float32](ctx, "replay", NewReplayEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "replay32", NewReplayEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "replay64", NewReplayEmbedder[float64])
+}
+
+// NewRecordEmbedder creates a new `RecordEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	record://?client-uri={CLIENT_URI}&path={PATH}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `path` – The path of the fixtures file. New fixtures are appended to the file if it already exists. Required.
+// * `truncate` – A boolean flag indicating that any existing fixtures should be removed. Default is false.
+func NewRecordEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	if !q.Has("path") {
+		return nil, fmt.Errorf("Missing ?path= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+	path := q.Get("path")
+
+	truncate := false
+
+	if q.Has("truncate") {
+
+		v, err := strconv.ParseBool(q.Get("truncate"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?truncate= parameter, %w", err)
+		}
+
+		truncate = v
+	}
+
+	flags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	recorded := make(map[string]bool)
+
+	if truncate {
+
+		flags = flags | os.O_TRUNC
+
+	} else {
+
+		// Don't record the same fixture twice
+
+		fixtures, err := readReplayFixtures[T](path, true)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for k := range fixtures {
+			recorded[k] = true
+		}
+	}
+
+	// Open the fixtures file before creating the underlying embedder so that it is not left running if the file can not be opened
+
+	wr, err := os.OpenFile(path, flags, 0644)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open fixtures for writing, %w", err)
+	}
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		wr.Close()
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &RecordEmbedder[T]{
+		embedder: emb,
+		wr:       wr,
+		enc:      json.NewEncoder(wr),
+		recorded: recorded,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings derives text embeddings using the underlying embedder and records the result.
+func (e *RecordEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "text", e.embedder.TextEmbeddings)
+}
+
+// ImageEmbeddings derives image embeddings using the underlying embedder and records the result.
+func (e *RecordEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "image", e.embedder.ImageEmbeddings)
+}
+
+// Close closes the fixtures file and the underlying embedder.
+func (e *RecordEmbedder[T]) Close(ctx context.Context) error {
+
+	e.mu.Lock()
+	err := e.wr.Close()
+	e.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close fixtures, %w", err)
+	}
+
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *RecordEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, modality string, fn func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	rsp, err := fn(ctx, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := newReplayFixture[T](req, modality)
+	f.ResponseModel = rsp.Model()
+	f.Precision = rsp.Precision()
+	f.Created = rsp.Created()
+	f.Embeddings = rsp.Embeddings()
+
+	k := f.key()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.recorded[k] {
+		return rsp, nil
+	}
+
+	err = e.enc.Encode(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to record fixture, %w", err)
+	}
+
+	e.recorded[k] = true
+	return rsp, nil
+}
+
+// NewReplayEmbedder creates a new `ReplayEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	replay://?path={PATH}
+//
+// Valid parameters are:
+// * `path` – The path of a fixtures file created by the `RecordEmbedder`. Required.
+func NewReplayEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("path") {
+		return nil, fmt.Errorf("Missing ?path= parameter")
+	}
+
+	fixtures, err := readReplayFixtures[T](q.Get("path"), false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e := &ReplayEmbedder[T]{
+		fixtures: fixtures,
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings returns the recorded text embeddings for 'req'.
+func (e *ReplayEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(req, "text")
+}
+
+// ImageEmbeddings returns the recorded image embeddings for 'req'.
+func (e *ReplayEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(req, "image")
+}
+
+func (e *ReplayEmbedder[T]) embeddings(req *EmbeddingsRequest, modality string) (EmbeddingsResponse[T], error) {
+
+	k := newReplayFixture[T](req, modality)
+
+	f, exists := e.fixtures[k.key()]
+
+	if !exists {
+		return nil, fmt.Errorf("No recorded fixture for %s request with model '%s', task '%s' and hash %s", modality, k.Model, k.Task, k.Hash)
+	}
+
+	// Embeddings may be recorded at one precision and replayed at another
+
+	target := fmt.Sprintf("float%d", floatWidth[T]()*8)
+	precision, _, _ := strings.Cut(f.Precision, "#")
+
+	switch precision {
+	case "", target:
+		precision = target
+	default:
+		precision = fmt.Sprintf("%s#as-%s", precision, target)
+	}
+
+	rsp := &CommonEmbeddingsResponse[T]{
+		CommonId:         req.Id,
+		CommonEmbeddings: slices.Clone(f.Embeddings),
+		CommonModel:      f.ResponseModel,
+		CommonCreated:    f.Created,
+		CommonPrecision:  precision,
+	}
+
+	return rsp, nil
+}
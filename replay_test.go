@@ -0,0 +1,194 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplayEmbedder(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "fixtures.jsonl")
+
+	record_uri := fmt.Sprintf("record://?client-uri=testing://%%3Fmodel=record-test%%26dimensions=8&path=%s", url.QueryEscape(path))
+
+	rec, err := NewEmbedder32(ctx, record_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create record embedder, %v", err)
+	}
+
+	text_req := &EmbeddingsRequest{
+		Id:   "a",
+		Body: []byte("Hello world"),
+	}
+
+	image_req := &EmbeddingsRequest{
+		Id:   "b",
+		Body: []byte("Not really an image"),
+	}
+
+	recorded, err := rec.TextEmbeddings(ctx, text_req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	// Repeated requests are only recorded once
+
+	for i := 0; i < 2; i++ {
+
+		_, err = rec.ImageEmbeddings(ctx, image_req)
+
+		if err != nil {
+			t.Fatalf("Failed to derive image embeddings, %v", err)
+		}
+	}
+
+	err = CloseEmbedder(ctx, rec)
+
+	if err != nil {
+		t.Fatalf("Failed to close record embedder, %v", err)
+	}
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read fixtures, %v", err)
+	}
+
+	if strings.Count(string(body), "\n") != 2 {
+		t.Fatalf("Expected 2 fixtures, got:\n%s", string(body))
+	}
+
+	replay_uri := fmt.Sprintf("replay://?path=%s", url.QueryEscape(path))
+
+	rep, err := NewEmbedder32(ctx, replay_uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create replay embedder, %v", err)
+	}
+
+	replay_req := &EmbeddingsRequest{
+		Id:   "c",
+		Body: []byte("Hello world"),
+	}
+
+	replayed, err := rep.TextEmbeddings(ctx, replay_req)
+
+	if err != nil {
+		t.Fatalf("Failed to replay embeddings, %v", err)
+	}
+
+	if replayed.Id() != "c" || replayed.Model() != "record-test" || replayed.Precision() != recorded.Precision() {
+		t.Fatalf("Unexpected response, %s %s %s", replayed.Id(), replayed.Model(), replayed.Precision())
+	}
+
+	if !slices.Equal(replayed.Embeddings(), recorded.Embeddings()) {
+		t.Fatalf("Replayed embeddings do not match, %v, %v", replayed.Embeddings(), recorded.Embeddings())
+	}
+
+	_, err = rep.ImageEmbeddings(ctx, image_req)
+
+	if err != nil {
+		t.Fatalf("Failed to replay image embeddings, %v", err)
+	}
+
+	// Misses fail: a different body, modality or model
+
+	misses := []func() error{
+		func() error {
+			_, err := rep.TextEmbeddings(ctx, &EmbeddingsRequest{Body: []byte("Goodbye world")})
+			return err
+		},
+		func() error {
+			_, err := rep.ImageEmbeddings(ctx, text_req)
+			return err
+		},
+		func() error {
+			_, err := rep.TextEmbeddings(ctx, &EmbeddingsRequest{Model: "other", Body: []byte("Hello world")})
+			return err
+		},
+	}
+
+	for idx, fn := range misses {
+
+		if fn() == nil {
+			t.Fatalf("Expected miss %d to fail", idx)
+		}
+	}
+
+	// Replay at a different precision
+
+	rep64, err := NewEmbedder64(ctx, "replay64://?path="+url.QueryEscape(path))
+
+	if err != nil {
+		t.Fatalf("Failed to create replay64 embedder, %v", err)
+	}
+
+	replayed64, err := rep64.TextEmbeddings(ctx, replay_req)
+
+	if err != nil {
+		t.Fatalf("Failed to replay embeddings, %v", err)
+	}
+
+	if replayed64.Precision() != "float32#as-float64" {
+		t.Fatalf("Unexpected precision, %s", replayed64.Precision())
+	}
+}
+
+func TestReplayEmbedderMissingFixtures(t *testing.T) {
+
+	ctx := context.Background()
+
+	_, err := NewEmbedder32(ctx, "replay://?path="+url.QueryEscape(filepath.Join(t.TempDir(), "missing.jsonl")))
+
+	if err == nil {
+		t.Fatalf("Expected missing fixtures to fail")
+	}
+}
+
+func TestReplayEmbedderNullFixture(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "fixtures.jsonl")
+
+	err := os.WriteFile(path, []byte("null\n"), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write fixtures, %v", err)
+	}
+
+	_, err = NewEmbedder32(ctx, "replay://?path="+url.QueryEscape(path))
+
+	if err == nil {
+		t.Fatalf("Expected null fixture to fail")
+	}
+}
+
+func TestRecordEmbedderUnwritableFixtures(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	path := filepath.Join(t.TempDir(), "missing", "fixtures.jsonl")
+
+	_, err := NewEmbedder32(ctx, "record://?client-uri=testing://&path="+url.QueryEscape(path))
+
+	if err == nil {
+		t.Fatalf("Expected unwritable fixtures to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected underlying embedder not to be left open")
+	}
+}
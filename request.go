@@ -4,4 +4,7 @@ type EmbeddingsRequest struct {
 	Id    string `json:"id,omitempty"`
 	Model string `json:"model"`
 	Body  []byte `json:"body"`
+	// Task is an optional value indicating the purpose of the embeddings (for example "query" or "document"). Embedders
+	// for models which expect task-specific prefixes or instructions use it to rewrite the text before it is embedded.
+	Task string `json:"task,omitempty"`
 }
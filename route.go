@@ -86,29 +86,10 @@ func NewRouteEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], er
 			return nil, fmt.Errorf("No models defined for client. ?client-uri= parameter must be in the form of '{CLIENT_URI}%s{MODEL}%s{MODEL}'", ROUTE_SEPARATOR, ROUTE_SEPARATOR)
 		}
 
-		var cl Embedder[T]
-		var stub T
+		cl, err := newEmbedderForPrecision[T](ctx, client_uri)
 
-		switch any(stub).(type) {
-		case float64:
-
-			cl64, err := NewEmbedder64(ctx, client_uri)
-
-			if err != nil {
-				return nil, fmt.Errorf("Failed to create new client for %s: %w", client_uri, err)
-			}
-
-			cl = any(cl64).(Embedder[T])
-
-		default:
-
-			cl32, err := NewEmbedder32(ctx, client_uri)
-
-			if err != nil {
-				return nil, fmt.Errorf("Failed to create new client for %s: %w", client_uri, err)
-			}
-
-			cl = any(cl32).(Embedder[T])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
 		}
 
 		for _, m := range models {
@@ -132,6 +113,30 @@ func NewRouteEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], er
 	return e, nil
 }
 
+// Close closes each of the underlying clients that implement the
+// EmbedderCloser interface.
+func (e *RouteEmbedder[T]) Close(ctx context.Context) error {
+
+	seen := make(map[Embedder[T]]bool)
+
+	for _, cl := range e.clients {
+
+		if seen[cl] {
+			continue
+		}
+
+		seen[cl] = true
+
+		err := CloseEmbedder(ctx, cl)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // TextEmbeddings implements the Embedder interface.  It forwards the
 // request to the underlying client that matches the requested model.
 func (e *RouteEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
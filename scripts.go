@@ -0,0 +1,182 @@
+package embeddings
+
+// Python helper scripts (and their requirements) used by the siglip, mlxclip and openclip implementations
+// are bundled with this package so that they can be installed on demand.
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SCRIPTS_VERSION is the version of the Python helper scripts expected by this package. Each script
+// declares its version in a "# go-embeddings-scripts-version: {VERSION}" comment and this value should
+// be incremented whenever the contract between the Go and Python code changes.
+const SCRIPTS_VERSION string = "2"
+
+const scripts_version_marker string = "# go-embeddings-scripts-version:"
+
+//go:embed *_py.txt *_requirements.txt
+var scripts_fs embed.FS
+
+// ScriptNames returns the list of (installed) filenames for the Python helper scripts and requirements
+// files bundled with this package. For example "siglip_cli.py" or "siglip_requirements.txt".
+func ScriptNames() []string {
+
+	names := make([]string, 0)
+
+	entries, _ := fs.ReadDir(scripts_fs, ".")
+
+	for _, e := range entries {
+		names = append(names, scriptInstallName(e.Name()))
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ReadScript returns the body of the bundled script or requirements file whose (installed) filename is 'name'.
+func ReadScript(name string) ([]byte, error) {
+
+	fname := name
+
+	if strings.HasSuffix(name, ".py") {
+		fname = fmt.Sprintf("%s_py.txt", strings.TrimSuffix(name, ".py"))
+	}
+
+	return scripts_fs.ReadFile(fname)
+}
+
+// WriteScripts writes all the Python helper scripts and requirements files bundled with this package to
+// the 'target' directory, creating it if necessary. Existing files will be overwritten.
+func WriteScripts(target string) error {
+
+	err := os.MkdirAll(target, 0755)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create %s, %w", target, err)
+	}
+
+	for _, name := range ScriptNames() {
+
+		body, err := ReadScript(name)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", name, err)
+		}
+
+		path := filepath.Join(target, name)
+
+		err = os.WriteFile(path, body, 0644)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write %s, %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// ScriptVersion returns the value of the "# go-embeddings-scripts-version:" comment in 'body' or an
+// empty string if it is not present.
+func ScriptVersion(body []byte) string {
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+
+	for scanner.Scan() {
+
+		ln := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(ln, scripts_version_marker) {
+			return strings.TrimSpace(strings.TrimPrefix(ln, scripts_version_marker))
+		}
+	}
+
+	return ""
+}
+
+// ensureScript returns the absolute path for 'path'. If 'path' is empty (or "/") then the bundled script
+// named 'name' will be written to a cache directory and that path will be returned instead. If 'path' is
+// not empty and the version of the script it points to does not match `SCRIPTS_VERSION` a warning is logged.
+func ensureScript(path string, name string) (string, error) {
+
+	if path == "" || path == "/" {
+		return installScript(name)
+	}
+
+	abs_path, err := filepath.Abs(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	body, err := os.ReadFile(abs_path)
+
+	if err != nil {
+		return "", err
+	}
+
+	v := ScriptVersion(body)
+
+	if v != SCRIPTS_VERSION {
+		slog.Warn("Script version does not match the version expected by go-embeddings, consider reinstalling scripts", "path", abs_path, "version", v, "expected", SCRIPTS_VERSION)
+	}
+
+	return abs_path, nil
+}
+
+func installScript(name string) (string, error) {
+
+	body, err := ReadScript(name)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to read bundled script %s, %w", name, err)
+	}
+
+	cache_dir, err := os.UserCacheDir()
+
+	if err != nil {
+		cache_dir = os.TempDir()
+	}
+
+	root := filepath.Join(cache_dir, "go-embeddings", "scripts", SCRIPTS_VERSION)
+
+	err = os.MkdirAll(root, 0755)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to create %s, %w", root, err)
+	}
+
+	path := filepath.Join(root, name)
+
+	existing, err := os.ReadFile(path)
+
+	if err == nil && bytes.Equal(existing, body) {
+		return path, nil
+	}
+
+	err = os.WriteFile(path, body, 0644)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to write %s, %w", path, err)
+	}
+
+	slog.Debug("Installed bundled script", "path", path)
+	return path, nil
+}
+
+func scriptInstallName(fname string) string {
+
+	if strings.HasSuffix(fname, "_py.txt") {
+		return fmt.Sprintf("%s.py", strings.TrimSuffix(fname, "_py.txt"))
+	}
+
+	return fname
+}
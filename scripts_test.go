@@ -0,0 +1,39 @@
+package embeddings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteScripts(t *testing.T) {
+
+	target := t.TempDir()
+
+	err := WriteScripts(target)
+
+	if err != nil {
+		t.Fatalf("Failed to write scripts, %v", err)
+	}
+
+	for _, name := range ScriptNames() {
+
+		path := filepath.Join(target, name)
+
+		body, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatalf("Failed to read %s, %v", path, err)
+		}
+
+		if filepath.Ext(name) != ".py" {
+			continue
+		}
+
+		v := ScriptVersion(body)
+
+		if v != SCRIPTS_VERSION {
+			t.Fatalf("Unexpected version for %s: '%s'", name, v)
+		}
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -25,6 +24,7 @@ type SigLIPCommandLineEmbedder[T Float] struct {
 	embeddings_py string
 	model         string
 	precision     string
+	protocol      string
 }
 
 func NewSigLIPCommandLineEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
@@ -37,13 +37,9 @@ func NewSigLIPCommandLineEmbedder[T Float](ctx context.Context, uri string) (Emb
 
 	q := u.Query()
 
-	embeddings_py, err := filepath.Abs(u.Path)
+	// If the path is empty use the script bundled with this package
 
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = os.Stat(embeddings_py)
+	embeddings_py, err := ensureScript(u.Path, "siglip_cli.py")
 
 	if err != nil {
 		return nil, err
@@ -80,11 +76,25 @@ func NewSigLIPCommandLineEmbedder[T Float](ctx context.Context, uri string) (Emb
 		precision = fmt.Sprintf("%s#as-float%d", precision, 64)
 	}
 
+	protocol := COMMAND_LINE_PROTOCOL_STDIO
+
+	if q.Has("protocol") {
+
+		protocol = q.Get("protocol")
+
+		err := validateCommandLineProtocol(protocol)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	e := &SigLIPCommandLineEmbedder[T]{
 		python:        python,
 		embeddings_py: embeddings_py,
 		precision:     precision,
 		model:         model,
+		protocol:      protocol,
 	}
 
 	return e, nil
@@ -92,11 +102,19 @@ func NewSigLIPCommandLineEmbedder[T Float](ctx context.Context, uri string) (Emb
 
 func (e *SigLIPCommandLineEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
-	return e.generateEmbeddingsFromCommandLine(ctx, req, "text", string(req.Body))
+	if e.protocol == COMMAND_LINE_PROTOCOL_FILE {
+		return e.generateEmbeddingsFromCommandLine(ctx, req, "text", string(req.Body))
+	}
+
+	return e.generateEmbeddingsFromStdio(ctx, req, "text")
 }
 
 func (e *SigLIPCommandLineEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
+	if e.protocol != COMMAND_LINE_PROTOCOL_FILE {
+		return e.generateEmbeddingsFromStdio(ctx, req, "image")
+	}
+
 	tmp, err := os.CreateTemp("", "siglip.*.img")
 
 	if err != nil {
@@ -144,11 +162,10 @@ func (e *SigLIPCommandLineEmbedder[T]) generateEmbeddingsFromCommandLine(ctx con
 		"--output", tmp.Name(),
 	}
 
-	cmd := exec.CommandContext(ctx, e.python, args...)
-	err = cmd.Run()
+	err = runCommandLine(ctx, e.python, args)
 
 	if err != nil {
-		return nil, fmt.Errorf("Failed to derive embeddings, %w", err)
+		return nil, err
 	}
 
 	r, err := os.Open(tmp.Name())
@@ -168,6 +185,35 @@ func (e *SigLIPCommandLineEmbedder[T]) generateEmbeddingsFromCommandLine(ctx con
 		return nil, fmt.Errorf("Failed to unmarshal embeddings, %w (%s)", err, tmp.Name())
 	}
 
+	return e.embeddingsResponse(req, e.model, e64), nil
+}
+
+func (e *SigLIPCommandLineEmbedder[T]) generateEmbeddingsFromStdio(ctx context.Context, req *EmbeddingsRequest, modality string) (EmbeddingsResponse[T], error) {
+
+	args := []string{
+		e.embeddings_py,
+		"--model_name", e.model,
+		"--stdio",
+	}
+
+	cl_req := newCommandLineEmbeddingsRequest(req, modality)
+	cl_rsp, err := runCommandLineEmbeddings(ctx, e.python, args, cl_req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	model := e.model
+
+	if cl_rsp.Model != "" {
+		model = cl_rsp.Model
+	}
+
+	return e.embeddingsResponse(req, model, cl_rsp.Embeddings), nil
+}
+
+func (e *SigLIPCommandLineEmbedder[T]) embeddingsResponse(req *EmbeddingsRequest, model string, e64 []float64) EmbeddingsResponse[T] {
+
 	now := time.Now()
 	ts := now.Unix()
 
@@ -175,7 +221,7 @@ func (e *SigLIPCommandLineEmbedder[T]) generateEmbeddingsFromCommandLine(ctx con
 		CommonId:        req.Id,
 		CommonPrecision: e.precision,
 		CommonCreated:   ts,
-		CommonModel:     e.model,
+		CommonModel:     model,
 	}
 
 	switch {
@@ -185,5 +231,5 @@ func (e *SigLIPCommandLineEmbedder[T]) generateEmbeddingsFromCommandLine(ctx con
 		rsp.CommonEmbeddings = toFloat64Slice[T](e64)
 	}
 
-	return rsp, nil
+	return rsp
 }
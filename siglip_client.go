@@ -19,6 +19,7 @@ func init() {
 type SigLIPLocalClientEmbedder[T Float] struct {
 	Embedder[T]
 	client    *LocalClient
+	server    *LocalServer
 	precision string
 }
 
@@ -32,13 +33,7 @@ func NewSigLIPLocalClientEmbedder[T Float](ctx context.Context, uri string) (Emb
 
 	q := u.Query()
 
-	client_uri := "http://localhost:5000"
-
-	if q.Has("server-uri") {
-		client_uri = q.Get("server-uri")
-	}
-
-	cl, err := NewLocalClient(ctx, client_uri)
+	cl, server, err := newLocalClientFromQuery(ctx, q, "siglip_server.py")
 
 	if err != nil {
 		return nil, err
@@ -52,12 +47,23 @@ func NewSigLIPLocalClientEmbedder[T Float](ctx context.Context, uri string) (Emb
 
 	e := &SigLIPLocalClientEmbedder[T]{
 		client:    cl,
+		server:    server,
 		precision: precision,
 	}
 
 	return e, nil
 }
 
+// Close stops the local server launched by 'e', if present.
+func (e *SigLIPLocalClientEmbedder[T]) Close(ctx context.Context) error {
+
+	if e.server == nil {
+		return nil
+	}
+
+	return e.server.Close(ctx)
+}
+
 func (e *SigLIPLocalClientEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
 
 	cl_req := &LocalClientEmbeddingRequest{
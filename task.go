@@ -0,0 +1,277 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// Embeddings for a search query.
+	TASK_QUERY string = "query"
+	// Embeddings for a document (or passage) to be searched.
+	TASK_DOCUMENT string = "document"
+	// Embeddings used as input features for classification.
+	TASK_CLASSIFICATION string = "classification"
+	// Embeddings used for clustering.
+	TASK_CLUSTERING string = "clustering"
+)
+
+// The placeholder in a `TaskTemplate` string which is replaced by the text being embedded.
+const TASK_TEMPLATE_TEXT string = "{text}"
+
+// TaskTemplate defines the text templates used by a family of models for each task. Templates
+// contain the `{text}` placeholder which is replaced by the text being embedded. An empty template
+// means that text is passed through without modification.
+type TaskTemplate struct {
+	// The unique name of the template.
+	Name string
+	// A regular expression used to match model names to the template.
+	Pattern *regexp.Regexp
+	// The template for search queries.
+	Query string
+	// The template for documents.
+	Document string
+	// The template for classification.
+	Classification string
+	// The template for clustering.
+	Clustering string
+}
+
+var task_templates = make([]*TaskTemplate, 0)
+var task_templates_mu = new(sync.RWMutex)
+
+func init() {
+	ctx := context.Background()
+
+	builtin := []*TaskTemplate{
+		{
+			// https://huggingface.co/nomic-ai/nomic-embed-text-v1.5
+			Name:           "nomic-embed",
+			Pattern:        regexp.MustCompile(`(?i)nomic-embed`),
+			Query:          "search_query: {text}",
+			Document:       "search_document: {text}",
+			Classification: "classification: {text}",
+			Clustering:     "clustering: {text}",
+		},
+		{
+			// https://huggingface.co/intfloat/e5-large-v2
+			Name:           "e5",
+			Pattern:        regexp.MustCompile(`(?i)(^|[/\-_])e5([\-_:]|$)`),
+			Query:          "query: {text}",
+			Document:       "passage: {text}",
+			Classification: "query: {text}",
+			Clustering:     "query: {text}",
+		},
+		{
+			// https://ai.google.dev/gemma/docs/embeddinggemma/model_card#prompt-instructions
+			Name:           "embeddinggemma",
+			Pattern:        regexp.MustCompile(`(?i)embeddinggemma`),
+			Query:          "task: search result | query: {text}",
+			Document:       "title: none | text: {text}",
+			Classification: "task: classification | query: {text}",
+			Clustering:     "task: clustering | query: {text}",
+		},
+		{
+			// https://huggingface.co/mixedbread-ai/mxbai-embed-large-v1
+			Name:    "mxbai-embed",
+			Pattern: regexp.MustCompile(`(?i)mxbai-embed`),
+			Query:   "Represent this sentence for searching relevant passages: {text}",
+		},
+	}
+
+	for _, t := range builtin {
+
+		err := RegisterTaskTemplate(ctx, t)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterTaskTemplate registers 't' so that it can be retrieved by the `TaskTemplateForModel` and `TaskTemplateByName`
+// methods. Registering a template with the same name as an existing template replaces it.
+func RegisterTaskTemplate(ctx context.Context, t *TaskTemplate) error {
+
+	if t.Name == "" {
+		return fmt.Errorf("Task template is missing a name")
+	}
+
+	if t.Pattern == nil {
+		return fmt.Errorf("Task template '%s' is missing a pattern", t.Name)
+	}
+
+	task_templates_mu.Lock()
+	defer task_templates_mu.Unlock()
+
+	for idx, existing := range task_templates {
+
+		if existing.Name == t.Name {
+			task_templates[idx] = t
+			return nil
+		}
+	}
+
+	task_templates = append(task_templates, t)
+	return nil
+}
+
+// TaskTemplateByName returns the registered `TaskTemplate` named 'name'.
+func TaskTemplateByName(name string) (*TaskTemplate, bool) {
+
+	task_templates_mu.RLock()
+	defer task_templates_mu.RUnlock()
+
+	for _, t := range task_templates {
+
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+// TaskTemplateForModel returns the first registered `TaskTemplate` whose pattern matches 'model'.
+func TaskTemplateForModel(model string) (*TaskTemplate, bool) {
+
+	if model == "" {
+		return nil, false
+	}
+
+	task_templates_mu.RLock()
+	defer task_templates_mu.RUnlock()
+
+	for _, t := range task_templates {
+
+		if t.Pattern.MatchString(model) {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+// Apply returns 'body' rewritten using the template for 'task'. If 'task' is empty, or there is no template
+// for 'task', or 'body' already starts with the template's prefix, then 'body' is returned unchanged.
+func (t *TaskTemplate) Apply(task string, body []byte) ([]byte, error) {
+
+	var tmpl string
+
+	switch task {
+	case "":
+		return body, nil
+	case TASK_QUERY:
+		tmpl = t.Query
+	case TASK_DOCUMENT:
+		tmpl = t.Document
+	case TASK_CLASSIFICATION:
+		tmpl = t.Classification
+	case TASK_CLUSTERING:
+		tmpl = t.Clustering
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported task '%s'", task)
+	}
+
+	if tmpl == "" {
+		return body, nil
+	}
+
+	prefix, suffix, _ := strings.Cut(tmpl, TASK_TEMPLATE_TEXT)
+	text := string(body)
+
+	if prefix != "" && strings.HasPrefix(text, prefix) {
+		return body, nil
+	}
+
+	return []byte(prefix + text + suffix), nil
+}
+
+// IsValidTask returns a boolean value indicating whether 'task' is a known task.
+func IsValidTask(task string) bool {
+
+	switch task {
+	case TASK_QUERY, TASK_DOCUMENT, TASK_CLASSIFICATION, TASK_CLUSTERING:
+		return true
+	default:
+		return false
+	}
+}
+
+// taskOptions defines the task-related options shared by embedders which support task templates.
+type taskOptions struct {
+	// An explicit template, overriding any template matched by model name.
+	template *TaskTemplate
+	// The task to use when a request does not specify one.
+	task string
+}
+
+// taskOptionsFromQuery derives a `taskOptions` instance from the `?task-template=` and `?task=` parameters in 'q'.
+func taskOptionsFromQuery(q url.Values) (*taskOptions, error) {
+
+	opts := &taskOptions{}
+
+	if q.Has("task-template") {
+
+		t, exists := TaskTemplateByName(q.Get("task-template"))
+
+		if !exists {
+			return nil, fmt.Errorf("Invalid or unsupported ?task-template= parameter")
+		}
+
+		opts.template = t
+	}
+
+	if q.Has("task") {
+
+		task := q.Get("task")
+
+		if !IsValidTask(task) {
+			return nil, fmt.Errorf("Invalid or unsupported ?task= parameter")
+		}
+
+		opts.task = task
+	}
+
+	return opts, nil
+}
+
+// apply returns the body of 'req' rewritten for the request (or default) task using either the explicit
+// template or the template matching 'model'.
+func (opts *taskOptions) apply(req *EmbeddingsRequest, model string) ([]byte, error) {
+
+	task := req.Task
+
+	if task == "" {
+		task = opts.task
+	}
+
+	if task == "" {
+		return req.Body, nil
+	}
+
+	if !IsValidTask(task) {
+		return nil, fmt.Errorf("Invalid or unsupported task '%s'", task)
+	}
+
+	t := opts.template
+
+	if t == nil {
+
+		v, exists := TaskTemplateForModel(model)
+
+		if !exists {
+			slog.Debug("No task template for model, passing text through", "model", model, "task", task)
+			return req.Body, nil
+		}
+
+		t = v
+	}
+
+	return t.Apply(task, req.Body)
+}
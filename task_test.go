@@ -0,0 +1,194 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTaskTemplateForModel(t *testing.T) {
+
+	tests := map[string]string{
+		"nomic-embed-text":               "nomic-embed",
+		"nomic-ai/nomic-embed-text-v1.5": "nomic-embed",
+		"intfloat/e5-large-v2":           "e5",
+		"intfloat/multilingual-e5-large": "e5",
+		"embeddinggemma":                 "embeddinggemma",
+		"google/embeddinggemma-300m":     "embeddinggemma",
+		"mxbai-embed-large":              "mxbai-embed",
+		"all-minilm":                     "",
+		"google/siglip-base-patch16-224": "",
+	}
+
+	for model, expected := range tests {
+
+		tmpl, exists := TaskTemplateForModel(model)
+
+		switch {
+		case expected == "" && exists:
+			t.Fatalf("Unexpected template '%s' for %s", tmpl.Name, model)
+		case expected != "" && !exists:
+			t.Fatalf("Missing template for %s", model)
+		case exists && tmpl.Name != expected:
+			t.Fatalf("Unexpected template for %s, expected '%s' but got '%s'", model, expected, tmpl.Name)
+		}
+	}
+}
+
+func TestTaskTemplateApply(t *testing.T) {
+
+	tmpl, exists := TaskTemplateByName("embeddinggemma")
+
+	if !exists {
+		t.Fatalf("Missing embeddinggemma template")
+	}
+
+	tests := map[string]string{
+		"":                  "Hello world",
+		TASK_QUERY:          "task: search result | query: Hello world",
+		TASK_DOCUMENT:       "title: none | text: Hello world",
+		TASK_CLASSIFICATION: "task: classification | query: Hello world",
+		TASK_CLUSTERING:     "task: clustering | query: Hello world",
+	}
+
+	for task, expected := range tests {
+
+		body, err := tmpl.Apply(task, []byte("Hello world"))
+
+		if err != nil {
+			t.Fatalf("Failed to apply template for task '%s', %v", task, err)
+		}
+
+		if string(body) != expected {
+			t.Fatalf("Unexpected body for task '%s': %s", task, string(body))
+		}
+
+		// Applying a template twice should not add a second prefix
+
+		body, err = tmpl.Apply(task, body)
+
+		if err != nil {
+			t.Fatalf("Failed to re-apply template for task '%s', %v", task, err)
+		}
+
+		if string(body) != expected {
+			t.Fatalf("Unexpected body re-applying task '%s': %s", task, string(body))
+		}
+	}
+
+	_, err := tmpl.Apply("summarization", []byte("Hello world"))
+
+	if err == nil {
+		t.Fatalf("Expected invalid task to fail")
+	}
+}
+
+func TestTaskOptions(t *testing.T) {
+
+	q := make(map[string][]string)
+	q["task"] = []string{TASK_DOCUMENT}
+
+	opts, err := taskOptionsFromQuery(q)
+
+	if err != nil {
+		t.Fatalf("Failed to derive task options, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	body, err := opts.apply(req, "nomic-embed-text")
+
+	if err != nil {
+		t.Fatalf("Failed to apply default task, %v", err)
+	}
+
+	if string(body) != "search_document: Hello world" {
+		t.Fatalf("Unexpected body for default task: %s", string(body))
+	}
+
+	req.Task = TASK_QUERY
+
+	body, err = opts.apply(req, "nomic-embed-text")
+
+	if err != nil {
+		t.Fatalf("Failed to apply request task, %v", err)
+	}
+
+	if string(body) != "search_query: Hello world" {
+		t.Fatalf("Unexpected body for request task: %s", string(body))
+	}
+
+	body, err = opts.apply(req, "all-minilm")
+
+	if err != nil {
+		t.Fatalf("Failed to apply task for unknown model, %v", err)
+	}
+
+	if string(body) != "Hello world" {
+		t.Fatalf("Unexpected body for unknown model: %s", string(body))
+	}
+
+	q["task-template"] = []string{"bogus"}
+
+	_, err = taskOptionsFromQuery(q)
+
+	if err == nil {
+		t.Fatalf("Expected invalid task template to fail")
+	}
+}
+
+func TestTaskTemplateURIModel(t *testing.T) {
+
+	ctx := context.Background()
+
+	var content string
+
+	handler := func(rsp http.ResponseWriter, req *http.Request) {
+
+		var ll_req *llamafileEmbeddingRequest
+
+		err := json.NewDecoder(req.Body).Decode(&ll_req)
+
+		if err != nil {
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		content = ll_req.Content
+
+		json.NewEncoder(rsp).Encode(&llamafileEmbeddingResponse{
+			Embeddings: []float64{1, 2, 3},
+		})
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(handler))
+	defer s.Close()
+
+	uri := fmt.Sprintf("llamafile://?client-uri=%s&model=nomic-embed-text&task=query", url.QueryEscape(s.URL))
+
+	emb, err := NewEmbedder64(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if content != "search_query: Hello world" {
+		t.Fatalf("Expected task template for URI model to be applied, got '%s'", content)
+	}
+}
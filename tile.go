@@ -0,0 +1,412 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// Split images in to a grid of equally-sized regions.
+	TILE_GRID string = "grid"
+	// Split images in to (possibly overlapping) square regions using a sliding window.
+	TILE_SLIDING string = "sliding"
+	// Split images in to five square regions: the center and each of the four corners.
+	TILE_CORNERS string = "corners"
+)
+
+// ImageRegion defines the bounding box of a region of an image, in pixels, after EXIF orientation has been applied.
+type ImageRegion struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// EmbeddingsRegion defines the embeddings for a region of an image and its bounding box.
+type EmbeddingsRegion[T Float] struct {
+	ImageRegion
+	Embeddings []T `json:"embeddings"`
+}
+
+// TiledEmbeddingsResponse is an `EmbeddingsResponse` implementation which includes the embeddings
+// (and bounding boxes) for each region of an image used to derive the final embeddings.
+type TiledEmbeddingsResponse[T Float] struct {
+	CommonEmbeddingsResponse[T]
+	Regions []*EmbeddingsRegion[T] `json:"regions,omitempty"`
+}
+
+// TileEmbedder implements the `Embedder` interface by splitting images in to regions, deriving embeddings for
+// each region using another `Embedder` instance and then pooling those embeddings.
+type TileEmbedder[T Float] struct {
+	Embedder[T]
+	embedder        Embedder[T]
+	method          string
+	rows            int
+	columns         int
+	window          float64
+	stride          float64
+	full            bool
+	pool            string
+	include_regions bool
+	format          string
+	quality         int
+	max_pixels      int
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "tile", NewTileEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "tile32", NewTileEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "tile64", NewTileEmbedder[float64])
+}
+
+// NewTileEmbedder creates a new `TileEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	tile://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings for each region. Required.
+// * `method` – The method used to split images. Valid options are "grid", "sliding" and "corners". Default is "grid".
+// * `rows` – The number of rows when using the "grid" method. Default is 2.
+// * `columns` – The number of columns when using the "grid" method. Default is 2.
+// * `window` – The size of each region, as a fraction of the image's shortest side, when using the "sliding" and "corners" methods. Default is 0.5.
+// * `stride` – The distance between regions, as a fraction of the image's shortest side, when using the "sliding" method. Default is half of `window`.
+// * `full` – A boolean flag indicating that the entire image should be included as an additional region. Default is false.
+// * `pool` – The method used to pool region embeddings. Valid options are "mean", "max", "weighted" (by area) and "none". Default is "mean".
+// * `regions` – A boolean flag indicating whether the embeddings for each region should be included in responses. Always true if `pool` is "none".
+// * `format` – The format used to encode each region. Valid options are "jpeg" and "png". Default is "jpeg".
+// * `quality` – The quality of JPEG-encoded regions. Default is 90.
+// * `max-pixels` – The maximum number of pixels (width x height) in source images. Default is `DEFAULT_IMAGE_MAX_PIXELS`.
+func NewTileEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	e := &TileEmbedder[T]{
+		method:     TILE_GRID,
+		rows:       2,
+		columns:    2,
+		window:     0.5,
+		pool:       POOL_MEAN,
+		format:     "jpeg",
+		quality:    90,
+		max_pixels: DEFAULT_IMAGE_MAX_PIXELS,
+	}
+
+	if q.Has("method") {
+		e.method = q.Get("method")
+	}
+
+	switch e.method {
+	case TILE_GRID, TILE_SLIDING, TILE_CORNERS:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?method= parameter")
+	}
+
+	int_params := map[string]*int{
+		"rows":       &e.rows,
+		"columns":    &e.columns,
+		"quality":    &e.quality,
+		"max-pixels": &e.max_pixels,
+	}
+
+	for k, ptr := range int_params {
+
+		if !q.Has(k) {
+			continue
+		}
+
+		v, err := strconv.Atoi(q.Get(k))
+
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("Invalid ?%s= parameter", k)
+		}
+
+		*ptr = v
+	}
+
+	if q.Has("window") {
+
+		v, err := strconv.ParseFloat(q.Get("window"), 64)
+
+		if err != nil || v <= 0 || v > 1 {
+			return nil, fmt.Errorf("Invalid ?window= parameter, must be greater than 0 and less than or equal to 1")
+		}
+
+		e.window = v
+	}
+
+	e.stride = e.window / 2
+
+	if q.Has("stride") {
+
+		v, err := strconv.ParseFloat(q.Get("stride"), 64)
+
+		if err != nil || v <= 0 || v > 1 {
+			return nil, fmt.Errorf("Invalid ?stride= parameter, must be greater than 0 and less than or equal to 1")
+		}
+
+		e.stride = v
+	}
+
+	if q.Has("full") {
+
+		v, err := strconv.ParseBool(q.Get("full"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?full= parameter, %w", err)
+		}
+
+		e.full = v
+	}
+
+	if q.Has("pool") {
+		e.pool = q.Get("pool")
+	}
+
+	switch e.pool {
+	case POOL_MEAN, POOL_MAX, POOL_WEIGHTED, POOL_NONE:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?pool= parameter")
+	}
+
+	e.include_regions = e.pool == POOL_NONE
+
+	if q.Has("regions") {
+
+		v, err := strconv.ParseBool(q.Get("regions"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?regions= parameter, %w", err)
+		}
+
+		e.include_regions = v || e.pool == POOL_NONE
+	}
+
+	if q.Has("format") {
+		e.format = q.Get("format")
+	}
+
+	switch e.format {
+	case "jpeg", "png":
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?format= parameter")
+	}
+
+	// Create the underlying embedder last so that it is not left running if any of the parameters above are invalid
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e.embedder = emb
+
+	return e, nil
+}
+
+// TextEmbeddings passes 'req' to the underlying embedder without modification.
+func (e *TileEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embedder.TextEmbeddings(ctx, req)
+}
+
+// ImageEmbeddings splits the body of 'req' in to regions, derives embeddings for each region and returns a
+// `TiledEmbeddingsResponse` instance. If the pooling method is "none" the response will have an empty
+// list of (top-level) embeddings and the embeddings for each region should be read from its `Regions` property.
+func (e *TileEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+
+	im, _, err := DecodeImage(req.Body, e.max_pixels)
+
+	if err != nil {
+		return nil, err
+	}
+
+	regions, err := TileImage(im.Bounds().Dx(), im.Bounds().Dy(), e.method, e.rows, e.columns, e.window, e.stride)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e.full {
+		regions = append(regions, ImageRegion{Width: im.Bounds().Dx(), Height: im.Bounds().Dy()})
+	}
+
+	rsp := &TiledEmbeddingsResponse[T]{
+		CommonEmbeddingsResponse: CommonEmbeddingsResponse[T]{
+			CommonId:      req.Id,
+			CommonCreated: time.Now().Unix(),
+		},
+	}
+
+	vectors := make([][]T, len(regions))
+	weights := make([]float64, len(regions))
+
+	for idx, r := range regions {
+
+		rect := image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+		region_im := CropImage(im, rect)
+
+		body, err := EncodeImage(region_im, e.format, e.quality)
+
+		if err != nil {
+			return nil, err
+		}
+
+		region_req := &EmbeddingsRequest{
+			Id:    req.Id,
+			Model: req.Model,
+			Body:  body,
+			Task:  req.Task,
+		}
+
+		region_rsp, err := e.embedder.ImageEmbeddings(ctx, region_req)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to derive embeddings for region %d (%v), %w", idx, rect, err)
+		}
+
+		rsp.CommonModel = region_rsp.Model()
+		rsp.CommonPrecision = region_rsp.Precision()
+
+		vectors[idx] = region_rsp.Embeddings()
+		weights[idx] = float64(r.Width * r.Height)
+
+		if e.include_regions {
+			rsp.Regions = append(rsp.Regions, &EmbeddingsRegion[T]{
+				ImageRegion: r,
+				Embeddings:  region_rsp.Embeddings(),
+			})
+		}
+	}
+
+	var pooled []T
+
+	switch e.pool {
+	case POOL_MAX:
+		pooled, err = MaxPool(vectors)
+	case POOL_WEIGHTED:
+		pooled, err = WeightedMeanPool(vectors, weights)
+	case POOL_NONE:
+		pooled = make([]T, 0)
+	default:
+		pooled, err = MeanPool(vectors)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to pool embeddings, %w", err)
+	}
+
+	rsp.CommonEmbeddings = pooled
+	return rsp, nil
+}
+
+// Close closes the underlying embedder.
+func (e *TileEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+// TileImage returns the list of regions for an image measuring 'width' x 'height' pixels using 'method' ("grid", "sliding" or "corners").
+// 'rows' and 'columns' are used by the "grid" method. 'window' and 'stride' are fractions of the shortest side of the image and are
+// used by the "sliding" method; the "corners" method only uses 'window'. If 'rows' or 'columns' exceed the height or width of the image
+// they are reduced so that every region is at least one pixel high and wide.
+func TileImage(width int, height int, method string, rows int, columns int, window float64, stride float64) ([]ImageRegion, error) {
+
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("Invalid image dimensions")
+	}
+
+	regions := make([]ImageRegion, 0)
+
+	shortest := min(width, height)
+	win := max(1, int(float64(shortest)*window))
+
+	switch method {
+	case TILE_GRID:
+
+		if rows < 1 || columns < 1 {
+			return nil, fmt.Errorf("Invalid number of rows or columns")
+		}
+
+		rows = min(rows, height)
+		columns = min(columns, width)
+
+		for r := 0; r < rows; r++ {
+
+			y0 := (r * height) / rows
+			y1 := ((r + 1) * height) / rows
+
+			for c := 0; c < columns; c++ {
+
+				x0 := (c * width) / columns
+				x1 := ((c + 1) * width) / columns
+
+				regions = append(regions, ImageRegion{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0})
+			}
+		}
+
+	case TILE_SLIDING:
+
+		step := max(1, int(float64(shortest)*stride))
+
+		for _, y := range slidingOffsets(height, win, step) {
+			for _, x := range slidingOffsets(width, win, step) {
+				regions = append(regions, ImageRegion{X: x, Y: y, Width: win, Height: win})
+			}
+		}
+
+	case TILE_CORNERS:
+
+		regions = append(regions,
+			ImageRegion{X: (width - win) / 2, Y: (height - win) / 2, Width: win, Height: win},
+			ImageRegion{X: 0, Y: 0, Width: win, Height: win},
+			ImageRegion{X: width - win, Y: 0, Width: win, Height: win},
+			ImageRegion{X: 0, Y: height - win, Width: win, Height: win},
+			ImageRegion{X: width - win, Y: height - win, Width: win, Height: win},
+		)
+
+	default:
+		return nil, fmt.Errorf("Invalid or unsupported tiling method '%s'", method)
+	}
+
+	return regions, nil
+}
+
+// slidingOffsets returns the offsets of windows of 'size' moving by 'step' along 'length', ensuring
+// the final window is aligned with the end of 'length'.
+func slidingOffsets(length int, size int, step int) []int {
+
+	offsets := make([]int, 0)
+
+	for o := 0; ; o += step {
+
+		if o+size >= length {
+			offsets = append(offsets, max(0, length-size))
+			break
+		}
+
+		offsets = append(offsets, o)
+	}
+
+	return offsets
+}
@@ -0,0 +1,119 @@
+package embeddings
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestTileImage(t *testing.T) {
+
+	tests := []struct {
+		method string
+		count  int
+	}{
+		{TILE_GRID, 6},
+		{TILE_SLIDING, 15},
+		{TILE_CORNERS, 5},
+	}
+
+	for _, test := range tests {
+
+		regions, err := TileImage(400, 300, test.method, 2, 3, 0.5, 0.25)
+
+		if err != nil {
+			t.Fatalf("Failed to tile image using %s, %v", test.method, err)
+		}
+
+		if len(regions) != test.count {
+			t.Fatalf("Unexpected number of regions for %s: %d", test.method, len(regions))
+		}
+
+		for _, r := range regions {
+
+			if r.X < 0 || r.Y < 0 || r.X+r.Width > 400 || r.Y+r.Height > 300 {
+				t.Fatalf("Region out of bounds for %s: %v", test.method, r)
+			}
+		}
+	}
+}
+
+func TestTileImageSmall(t *testing.T) {
+
+	regions, err := TileImage(3, 2, TILE_GRID, 4, 5, 0.5, 0.25)
+
+	if err != nil {
+		t.Fatalf("Failed to tile image, %v", err)
+	}
+
+	if len(regions) != 6 {
+		t.Fatalf("Unexpected number of regions: %d", len(regions))
+	}
+
+	for _, r := range regions {
+
+		if r.Width < 1 || r.Height < 1 {
+			t.Fatalf("Unexpected empty region: %v", r)
+		}
+	}
+}
+
+func TestTileImageEmbeddings(t *testing.T) {
+
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "tile://?client-uri=testing://&method=corners&full=true&regions=true")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	im_path := "fixtures/1527845303_walrus.jpg"
+
+	im_body, err := os.ReadFile(im_path)
+
+	if err != nil {
+		t.Fatalf("Failed to read data from %s, %v", im_path, err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: im_body,
+	}
+
+	rsp, err := emb.ImageEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	if len(rsp.Embeddings()) != 4 {
+		t.Fatalf("Unexpected embeddings, %v", rsp.Embeddings())
+	}
+
+	tiled_rsp, ok := rsp.(*TiledEmbeddingsResponse[float32])
+
+	if !ok {
+		t.Fatalf("Unexpected response type")
+	}
+
+	if len(tiled_rsp.Regions) != 6 {
+		t.Fatalf("Unexpected number of regions: %d", len(tiled_rsp.Regions))
+	}
+}
+
+func TestTileEmbedderInvalidParameters(t *testing.T) {
+
+	ctx := context.Background()
+
+	open := testing_open.Load()
+
+	_, err := NewEmbedder32(ctx, "tile://?client-uri=testing://&method=mosaic")
+
+	if err == nil {
+		t.Fatalf("Expected invalid ?method= parameter to fail")
+	}
+
+	if testing_open.Load() != open {
+		t.Fatalf("Expected underlying embedder not to be left open")
+	}
+}
@@ -0,0 +1,173 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// The name of the OpenTelemetry tracer used by `TraceEmbedder`.
+const TRACER_NAME string = "github.com/sfomuseum/go-embeddings"
+
+// TraceEmbedder implements the `Embedder` interface by creating an OpenTelemetry span around each request sent to another
+// `Embedder` instance. Spans are created using the global `TracerProvider` (see `otel.SetTracerProvider`).
+type TraceEmbedder[T Float] struct {
+	Embedder[T]
+	embedder Embedder[T]
+	scheme   string
+	model    string
+}
+
+func init() {
+	ctx := context.Background()
+
+	RegisterEmbedder[float32](ctx, "trace", NewTraceEmbedder[float32])
+	RegisterEmbedder[float32](ctx, "trace32", NewTraceEmbedder[float32])
+	RegisterEmbedder[float64](ctx, "trace64", NewTraceEmbedder[float64])
+}
+
+// NewTraceEmbedder creates a new `TraceEmbedder` instance from the supplied URI.
+// The URI must be in the form:
+//
+//	trace://?client-uri={CLIENT_URI}&{PARAMETERS}
+//
+// Valid parameters are:
+// * `client-uri` – The URI of the underlying `Embedder` used to derive embeddings. Required.
+// * `label` – The value of the "embeddings.scheme" span attribute. Default is the scheme of `client-uri`.
+func NewTraceEmbedder[T Float](ctx context.Context, uri string) (Embedder[T], error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("client-uri") {
+		return nil, fmt.Errorf("Missing ?client-uri= parameter")
+	}
+
+	client_uri := q.Get("client-uri")
+
+	client_u, err := url.Parse(client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse client URI, %w", err)
+	}
+
+	emb, err := newEmbedderForPrecision[T](ctx, client_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new client for %s: %w", embedderLabel(client_uri), err)
+	}
+
+	e := &TraceEmbedder[T]{
+		embedder: emb,
+		scheme:   client_u.Scheme,
+		model:    client_u.Query().Get("model"),
+	}
+
+	if q.Has("label") {
+		e.scheme = q.Get("label")
+	}
+
+	return e, nil
+}
+
+// TextEmbeddings passes 'req' to the underlying embedder inside an "embeddings.TextEmbeddings" span.
+func (e *TraceEmbedder[T]) TextEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "text", e.embedder.TextEmbeddings)
+}
+
+// ImageEmbeddings passes 'req' to the underlying embedder inside an "embeddings.ImageEmbeddings" span.
+func (e *TraceEmbedder[T]) ImageEmbeddings(ctx context.Context, req *EmbeddingsRequest) (EmbeddingsResponse[T], error) {
+	return e.embeddings(ctx, req, "image", e.embedder.ImageEmbeddings)
+}
+
+// Close closes the underlying embedder.
+func (e *TraceEmbedder[T]) Close(ctx context.Context) error {
+	return CloseEmbedder(ctx, e.embedder)
+}
+
+func (e *TraceEmbedder[T]) embeddings(ctx context.Context, req *EmbeddingsRequest, modality string, fn func(context.Context, *EmbeddingsRequest) (EmbeddingsResponse[T], error)) (EmbeddingsResponse[T], error) {
+
+	name := "embeddings.TextEmbeddings"
+
+	if modality == "image" {
+		name = "embeddings.ImageEmbeddings"
+	}
+
+	model := e.model
+
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	tracer := otel.Tracer(TRACER_NAME)
+
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("embeddings.scheme", e.scheme),
+			attribute.String("embeddings.modality", modality),
+			attribute.String("embeddings.id", req.Id),
+			attribute.Int("embeddings.body_size", len(req.Body)),
+		),
+	)
+
+	defer span.End()
+
+	rsp, err := fn(ctx, req)
+
+	if err != nil {
+		span.SetAttributes(attribute.String("embeddings.model", model))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if rsp.Model() != "" {
+		model = rsp.Model()
+	}
+
+	span.SetAttributes(
+		attribute.String("embeddings.model", model),
+		attribute.Int("embeddings.dimensions", len(rsp.Embeddings())),
+	)
+
+	return rsp, nil
+}
+
+// injectTraceHeaders adds the trace context in 'ctx', if any, to 'h' using the global OpenTelemetry propagator (see `otel.SetTextMapPropagator`).
+func injectTraceHeaders(ctx context.Context, h http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// injectTraceMetadata returns a copy of 'ctx' with its trace context, if any, added to the outgoing gRPC metadata using the global
+// OpenTelemetry propagator (see `otel.SetTextMapPropagator`).
+func injectTraceMetadata(ctx context.Context) context.Context {
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	if len(carrier) == 0 {
+		return ctx
+	}
+
+	kv := make([]string, 0, len(carrier)*2)
+
+	for k, v := range carrier {
+		kv = append(kv, k, v)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
@@ -0,0 +1,190 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/metadata"
+)
+
+func setupTestTracing(t *testing.T) *tracetest.InMemoryExporter {
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous_provider := otel.GetTracerProvider()
+	previous_propagator := otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous_provider)
+		otel.SetTextMapPropagator(previous_propagator)
+	})
+
+	return exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+
+	attrs := make(map[attribute.Key]attribute.Value)
+
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+func TestTraceEmbedder(t *testing.T) {
+
+	exporter := setupTestTracing(t)
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "trace://?client-uri=testing://%3Fmodel=trace-test%26dimensions=8")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Id:   "1",
+		Body: []byte("Hello world"),
+	}
+
+	_, err = emb.TextEmbeddings(ctx, req)
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	if spans[0].Name != "embeddings.TextEmbeddings" {
+		t.Fatalf("Unexpected span name, %s", spans[0].Name)
+	}
+
+	attrs := spanAttributes(spans[0])
+
+	if attrs["embeddings.scheme"].AsString() != "testing" {
+		t.Fatalf("Unexpected scheme attribute, %v", attrs["embeddings.scheme"])
+	}
+
+	if attrs["embeddings.model"].AsString() != "trace-test" {
+		t.Fatalf("Unexpected model attribute, %v", attrs["embeddings.model"])
+	}
+
+	if attrs["embeddings.body_size"].AsInt64() != 11 {
+		t.Fatalf("Unexpected body size attribute, %v", attrs["embeddings.body_size"])
+	}
+
+	if attrs["embeddings.dimensions"].AsInt64() != 8 {
+		t.Fatalf("Unexpected dimensions attribute, %v", attrs["embeddings.dimensions"])
+	}
+}
+
+func TestTraceEmbedderError(t *testing.T) {
+
+	exporter := setupTestTracing(t)
+	ctx := context.Background()
+
+	emb, err := NewEmbedder32(ctx, "trace://?client-uri=testing://%3Ferror=retryable")
+
+	if err != nil {
+		t.Fatalf("Failed to create embedder, %v", err)
+	}
+
+	req := &EmbeddingsRequest{
+		Body: []byte("Hello world"),
+	}
+
+	_, err = emb.ImageEmbeddings(ctx, req)
+
+	if err == nil {
+		t.Fatalf("Expected error")
+	}
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	if spans[0].Name != "embeddings.ImageEmbeddings" {
+		t.Fatalf("Unexpected span name, %s", spans[0].Name)
+	}
+
+	if spans[0].Status.Code != codes.Error {
+		t.Fatalf("Expected error status, got %v", spans[0].Status)
+	}
+
+	if len(spans[0].Events) == 0 {
+		t.Fatalf("Expected error to be recorded")
+	}
+}
+
+func TestTraceHeaders(t *testing.T) {
+
+	exporter := setupTestTracing(t)
+	ctx := context.Background()
+
+	var traceparent string
+
+	server := httptest.NewServer(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+
+		traceparent = req.Header.Get("traceparent")
+
+		rsp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rsp).Encode(&LocalClientEmbeddingResponse{
+			Model:      "local",
+			Embeddings: []float64{1, 2, 3},
+		})
+	}))
+
+	defer server.Close()
+
+	cl, err := NewLocalClient(ctx, server.URL)
+
+	if err != nil {
+		t.Fatalf("Failed to create local client, %v", err)
+	}
+
+	span_ctx, span := otel.Tracer(TRACER_NAME).Start(ctx, "test")
+
+	_, err = cl.embeddings(span_ctx, &LocalClientEmbeddingRequest{Content: "Hello world"})
+
+	span.End()
+
+	if err != nil {
+		t.Fatalf("Failed to derive embeddings, %v", err)
+	}
+
+	trace_id := exporter.GetSpans()[0].SpanContext.TraceID().String()
+
+	if traceparent == "" || traceparent[3:35] != trace_id {
+		t.Fatalf("Unexpected traceparent header '%s', expected trace ID %s", traceparent, trace_id)
+	}
+
+	// gRPC clients
+
+	md, ok := metadata.FromOutgoingContext(injectTraceMetadata(span_ctx))
+
+	if !ok || len(md.Get("traceparent")) != 1 {
+		t.Fatalf("Missing traceparent in outgoing gRPC metadata")
+	}
+}
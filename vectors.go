@@ -0,0 +1,170 @@
+package embeddings
+
+import (
+	"fmt"
+	"math"
+)
+
+// MeanPool returns the element-wise mean of 'vectors', all of which must have the same dimensions.
+func MeanPool[T Float](vectors [][]T) ([]T, error) {
+
+	weights := make([]float64, len(vectors))
+
+	for idx := range vectors {
+		weights[idx] = 1.0
+	}
+
+	return WeightedMeanPool(vectors, weights)
+}
+
+// WeightedMeanPool returns the element-wise mean of 'vectors', weighted by the corresponding value in 'weights'.
+// All the vectors must have the same dimensions.
+func WeightedMeanPool[T Float](vectors [][]T, weights []float64) ([]T, error) {
+
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("No vectors to pool")
+	}
+
+	if len(vectors) != len(weights) {
+		return nil, fmt.Errorf("Number of weights does not match number of vectors")
+	}
+
+	dims := len(vectors[0])
+	sum := make([]float64, dims)
+	total := 0.0
+
+	for idx, v := range vectors {
+
+		if len(v) != dims {
+			return nil, fmt.Errorf("Vector at offset %d has %d dimensions, expected %d", idx, len(v), dims)
+		}
+
+		w := weights[idx]
+		total += w
+
+		for i, f := range v {
+			sum[i] += float64(f) * w
+		}
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("Sum of weights is zero")
+	}
+
+	pooled := make([]T, dims)
+
+	for i, f := range sum {
+		pooled[i] = T(f / total)
+	}
+
+	return pooled, nil
+}
+
+// MaxPool returns the element-wise maximum of 'vectors', all of which must have the same dimensions.
+func MaxPool[T Float](vectors [][]T) ([]T, error) {
+
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("No vectors to pool")
+	}
+
+	dims := len(vectors[0])
+	pooled := make([]T, dims)
+	copy(pooled, vectors[0])
+
+	for idx, v := range vectors[1:] {
+
+		if len(v) != dims {
+			return nil, fmt.Errorf("Vector at offset %d has %d dimensions, expected %d", idx+1, len(v), dims)
+		}
+
+		for i, f := range v {
+			pooled[i] = max(pooled[i], f)
+		}
+	}
+
+	return pooled, nil
+}
+
+// Normalize returns a copy of 'vector' scaled to unit (L2) length. Zero-length vectors are returned unchanged.
+func Normalize[T Float](vector []T) []T {
+
+	norm := 0.0
+
+	for _, f := range vector {
+		norm += float64(f) * float64(f)
+	}
+
+	norm = math.Sqrt(norm)
+
+	normalized := make([]T, len(vector))
+
+	for i, f := range vector {
+
+		if norm == 0 {
+			normalized[i] = f
+			continue
+		}
+
+		normalized[i] = T(float64(f) / norm)
+	}
+
+	return normalized
+}
+
+// DotProduct returns the dot product of 'a' and 'b', which must have the same dimensions.
+func DotProduct[T Float](a []T, b []T) (float64, error) {
+
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("Vectors have different dimensions, %d and %d", len(a), len(b))
+	}
+
+	sum := 0.0
+
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum, nil
+}
+
+// CosineSimilarity returns the cosine similarity of 'a' and 'b', which must have the same dimensions. If either
+// vector has zero length the similarity is zero.
+func CosineSimilarity[T Float](a []T, b []T) (float64, error) {
+
+	dot, err := DotProduct(a, b)
+
+	if err != nil {
+		return 0, err
+	}
+
+	norm_a := 0.0
+	norm_b := 0.0
+
+	for i := range a {
+		norm_a += float64(a[i]) * float64(a[i])
+		norm_b += float64(b[i]) * float64(b[i])
+	}
+
+	if norm_a == 0 || norm_b == 0 {
+		return 0, nil
+	}
+
+	return dot / (math.Sqrt(norm_a) * math.Sqrt(norm_b)), nil
+}
+
+// EuclideanDistance returns the Euclidean (L2) distance between 'a' and 'b', which must have the same dimensions.
+func EuclideanDistance[T Float](a []T, b []T) (float64, error) {
+
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("Vectors have different dimensions, %d and %d", len(a), len(b))
+	}
+
+	sum := 0.0
+
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+
+	return math.Sqrt(sum), nil
+}